@@ -0,0 +1,91 @@
+package gluamapper
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/yuin/gopher-lua"
+)
+
+// HolePolicy is the policy of the nil holes in the Lua arrays like {1, nil, 3}.
+type HolePolicy int
+
+const (
+	// HoleDefault keeps the behavior which depends on the target:
+	// a slice has the length of the Lua length operator,
+	// interface{} has the max index, and an array reads its length of indexes.
+	HoleDefault HolePolicy = iota
+	// HoleError returns an error if the Lua array has a hole.
+	HoleError
+	// HoleStop stops the Lua array at the first hole.
+	HoleStop
+	// HoleFill fills the holes with the zero values up to the max index.
+	HoleFill
+)
+
+// minSparseArrayLen is the length from which a sparse Lua array
+// must have at least half of its elements set,
+// so that a key like [1e9] does not allocate a huge Go slice.
+const minSparseArrayLen = 1024
+
+// getLuaArrayBounds gets the first index and the length of the Lua array
+// by m.HolePolicy and m.SparseArrays.
+// defaultLen is the length for HoleDefault.
+// It is an error if a sparse array has too many holes,
+// or has a negative integer key or one out of the int range.
+// The other keys, like "name" and [1.5], are ignored.
+func (m *Mapper) getLuaArrayBounds(tbl *lua.LTable, defaultLen int) (int, int, error) {
+	if m.HolePolicy == HoleDefault && !m.SparseArrays {
+		return 1, defaultLen, nil
+	}
+
+	first, last := 1, tbl.MaxN()
+	count := 0 // the number of the array indexes if m.SparseArrays
+	if m.SparseArrays {
+		var err error
+		tbl.ForEach(func(lKey, _ lua.LValue) {
+			n, ok := lKey.(lua.LNumber)
+			if !ok || float64(n) != math.Trunc(float64(n)) {
+				return // not an integer key
+			}
+			if n < 0 || float64(n) != float64(int(n)) {
+				if err == nil {
+					err = fmt.Errorf("array index [%v] out of range", float64(n))
+				}
+				return
+			}
+			count++
+			if i := int(n); i == 0 {
+				first = 0
+			} else if i > last {
+				last = i
+			}
+		})
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+
+	end := first // the end of the sequence
+	for end <= last && rawGetIndex(tbl, end) != lua.LNil {
+		end++
+	}
+	switch m.HolePolicy {
+	case HoleError:
+		if end <= last {
+			return 0, 0, fmt.Errorf("hole at [%d]", end)
+		}
+	case HoleStop:
+		last = end - 1
+	}
+	n := last - first + 1
+	if m.SparseArrays && n > minSparseArrayLen && n > 2*count {
+		return 0, 0, fmt.Errorf("too sparse array: %d integer keys up to [%d]", count, last)
+	}
+	return first, n, nil
+}
+
+// rawGetIndex gets the value of the integer key, which may be out of the array part.
+func rawGetIndex(tbl *lua.LTable, i int) lua.LValue {
+	return tbl.RawGet(lua.LNumber(i))
+}
@@ -0,0 +1,88 @@
+package gluamapper
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yuin/gopher-lua"
+)
+
+func TestHolePolicy(t *testing.T) {
+	var err error
+	assert := require.New(t)
+	L := lua.NewState()
+	err = L.DoString(`holes = {1, nil, 3}`)
+	assert.NoError(err)
+	holes := L.GetGlobal("holes")
+
+	m := NewMapper()
+	var slc []int
+	var arr [4]int
+	var itf interface{}
+	assert.NoError(m.Map(holes, &slc))
+	assert.Equal([]int{1, 0, 3}, slc)
+
+	m.HolePolicy = HoleError
+	assert.EqualError(m.Map(holes, &slc), "hole at [2]")
+	assert.EqualError(m.Map(holes, &arr), "hole at [2]")
+	assert.EqualError(m.Map(holes, &itf), "hole at [2]")
+
+	m.HolePolicy = HoleStop
+	assert.NoError(m.Map(holes, &slc))
+	assert.Equal([]int{1}, slc)
+	assert.NoError(m.Map(holes, &arr))
+	assert.Equal([4]int{1}, arr)
+	assert.NoError(m.Map(holes, &itf))
+	assert.Equal([]interface{}{float64(1)}, itf)
+
+	m.HolePolicy = HoleFill
+	assert.NoError(m.Map(holes, &slc))
+	assert.Equal([]int{1, 0, 3}, slc)
+	assert.NoError(m.Map(holes, &arr))
+	assert.Equal([4]int{1, 0, 3}, arr)
+	assert.NoError(m.Map(holes, &itf))
+	assert.Equal([]interface{}{float64(1), nil, float64(3)}, itf)
+}
+
+func TestSparseArrays(t *testing.T) {
+	var err error
+	assert := require.New(t)
+	L := lua.NewState()
+	err = L.DoString(`
+		sparse = {}
+		sparse[5] = "e"
+		sparse[1] = "a"
+		zero_based = {[0] = "a", "b", "c"}
+		huge = {"a", [2^60] = "x"}
+		negative = {"a", [-5] = "x"}
+		overflow = {"a", [1e30] = "x"}
+		others = {"a", "b", name = "x", [1.5] = "y"}
+	`)
+	assert.NoError(err)
+
+	m := NewMapper()
+	m.SparseArrays = true
+	var slc []string
+	assert.NoError(m.Map(L.GetGlobal("sparse"), &slc))
+	assert.Equal([]string{"a", "", "", "", "e"}, slc)
+	assert.NoError(m.Map(L.GetGlobal("zero_based"), &slc))
+	assert.Equal([]string{"a", "b", "c"}, slc)
+
+	var itf interface{}
+	assert.NoError(m.Map(L.GetGlobal("zero_based"), &itf))
+	assert.Equal([]interface{}{"a", "b", "c"}, itf)
+	assert.EqualError(m.Map(L.GetGlobal("huge"), &slc), "too sparse array: 2 integer keys up to [1152921504606846976]")
+	assert.EqualError(m.Map(L.GetGlobal("huge"), &itf), "too sparse array: 2 integer keys up to [1152921504606846976]")
+	assert.EqualError(m.Map(L.GetGlobal("negative"), &slc), "array index [-5] out of range")
+	assert.EqualError(m.Map(L.GetGlobal("overflow"), &slc), "array index [1e+30] out of range")
+	assert.NoError(m.Map(L.GetGlobal("others"), &slc))
+	assert.Equal([]string{"a", "b"}, slc)
+
+	m.HolePolicy = HoleStop
+	assert.NoError(m.Map(L.GetGlobal("sparse"), &slc))
+	assert.Equal([]string{"a"}, slc)
+	assert.NoError(m.Map(L.GetGlobal("huge"), &slc))
+	assert.Equal([]string{"a"}, slc)
+	m.HolePolicy = HoleError
+	assert.EqualError(m.Map(L.GetGlobal("sparse"), &slc), "hole at [2]")
+}
@@ -0,0 +1,98 @@
+package gluamapper
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+
+	assert "github.com/arl/assertgo"
+	"github.com/yuin/gopher-lua"
+)
+
+var (
+	bigIntType   = reflect.TypeOf(big.Int{})
+	bigFloatType = reflect.TypeOf(big.Float{})
+	bigRatType   = reflect.TypeOf(big.Rat{})
+)
+
+// mapBigInt maps a decimal string or an integral Lua number into big.Int.
+func mapBigInt(lv lua.LValue, rv reflect.Value) error {
+	assert.True(rv.Type() == bigIntType)
+	z := rv.Addr().Interface().(*big.Int)
+	switch v := lv.(type) {
+	case lua.LString:
+		if _, ok := z.SetString(string(v), 10); !ok {
+			return fmt.Errorf("illegal %s %q", rv.Type(), string(v))
+		}
+		return nil
+	case lua.LNumber:
+		f := float64(v)
+		if math.IsInf(f, 0) || math.IsNaN(f) || f != math.Trunc(f) {
+			return fmt.Errorf("%v is not exactly representable by %s", v, rv.Type())
+		}
+		big.NewFloat(f).Int(z)
+		return nil
+	}
+	return newTypeError(lv, rv)
+}
+
+// mapBigFloat maps a decimal string or a Lua number into big.Float.
+// The precision of the big.Float is kept if it is not 0.
+// The Lua number is always exact, but the string is rounded to the precision,
+// which is at least 64 if the big.Float precision is 0.
+func mapBigFloat(lv lua.LValue, rv reflect.Value) error {
+	assert.True(rv.Type() == bigFloatType)
+	z := rv.Addr().Interface().(*big.Float)
+	switch v := lv.(type) {
+	case lua.LString:
+		r, ok := new(big.Rat).SetString(string(v))
+		if !ok {
+			return fmt.Errorf("illegal %s %q", rv.Type(), string(v))
+		}
+		z.SetRat(r)
+		return nil
+	case lua.LNumber:
+		if math.IsNaN(float64(v)) {
+			return fmt.Errorf("%v is not exactly representable by %s", v, rv.Type())
+		}
+		z.SetFloat64(float64(v))
+		return nil
+	}
+	return newTypeError(lv, rv)
+}
+
+// mapBigRat maps a string like "1.5" or "3/4", or a finite Lua number into big.Rat.
+func mapBigRat(lv lua.LValue, rv reflect.Value) error {
+	assert.True(rv.Type() == bigRatType)
+	z := rv.Addr().Interface().(*big.Rat)
+	switch v := lv.(type) {
+	case lua.LString:
+		if _, ok := z.SetString(string(v)); !ok {
+			return fmt.Errorf("illegal %s %q", rv.Type(), string(v))
+		}
+		return nil
+	case lua.LNumber:
+		if z.SetFloat64(float64(v)) == nil {
+			return fmt.Errorf("%v is not exactly representable by %s", v, rv.Type())
+		}
+		return nil
+	}
+	return newTypeError(lv, rv)
+}
+
+// encodeBigNumber encodes big.Int, big.Float or big.Rat into an exact Lua string.
+func encodeBigNumber(rv reflect.Value) lua.LValue {
+	if !rv.CanAddr() {
+		ptr := reflect.New(rv.Type())
+		ptr.Elem().Set(rv)
+		rv = ptr.Elem()
+	}
+	switch z := rv.Addr().Interface().(type) {
+	case *big.Int:
+		return lua.LString(z.String())
+	case *big.Float:
+		return lua.LString(z.Text('g', -1))
+	}
+	return lua.LString(rv.Addr().Interface().(*big.Rat).RatString())
+}
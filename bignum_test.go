@@ -0,0 +1,108 @@
+package gluamapper
+
+import (
+	"math"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yuin/gopher-lua"
+)
+
+func TestMapBigInt(t *testing.T) {
+	var err error
+	assert := require.New(t)
+
+	var n *big.Int
+	err = Map(lua.LString("123456789012345678901234567890"), &n)
+	assert.NoError(err)
+	assert.Equal("123456789012345678901234567890", n.String())
+	err = Map(lua.LNumber(1e20), &n)
+	assert.NoError(err)
+	assert.Equal("100000000000000000000", n.String())
+	err = Map(lua.LNumber(1.5), &n)
+	assert.EqualError(err, "1.5 is not exactly representable by big.Int")
+	err = Map(lua.LNumber(math.Inf(1)), &n)
+	assert.EqualError(err, "+Inf is not exactly representable by big.Int")
+	err = Map(lua.LString("12.3"), &n)
+	assert.EqualError(err, `illegal big.Int "12.3"`)
+	err = Map(lua.LTrue, &n)
+	assert.EqualError(err, "big.Int expected but got Lua boolean")
+
+	var v big.Int
+	err = Map(lua.LString("-42"), &v)
+	assert.NoError(err)
+	assert.Equal(int64(-42), v.Int64())
+}
+
+func TestMapBigFloat(t *testing.T) {
+	var err error
+	assert := require.New(t)
+
+	var f *big.Float
+	err = Map(lua.LString("1.5"), &f)
+	assert.NoError(err)
+	assert.Equal("1.5", f.Text('g', -1))
+	err = Map(lua.LNumber(0.1), &f)
+	assert.NoError(err)
+	v, accuracy := f.Float64()
+	assert.Equal(0.1, v)
+	assert.Equal(big.Exact, accuracy)
+	err = Map(lua.LNumber(math.NaN()), &f)
+	assert.EqualError(err, "NaN is not exactly representable by big.Float")
+	err = Map(lua.LString("abc"), &f)
+	assert.EqualError(err, `illegal big.Float "abc"`)
+}
+
+func TestMapBigRat(t *testing.T) {
+	var err error
+	assert := require.New(t)
+
+	var r *big.Rat
+	err = Map(lua.LString("0.1"), &r)
+	assert.NoError(err)
+	assert.Equal("1/10", r.String())
+	err = Map(lua.LString("3/4"), &r)
+	assert.NoError(err)
+	assert.Equal("3/4", r.String())
+	err = Map(lua.LNumber(0.5), &r)
+	assert.NoError(err)
+	assert.Equal("1/2", r.String())
+	err = Map(lua.LNumber(math.Inf(-1)), &r)
+	assert.EqualError(err, "-Inf is not exactly representable by big.Rat")
+}
+
+func TestEncodeBigNumber(t *testing.T) {
+	assert := require.New(t)
+	L := lua.NewState()
+	m := NewMapperWithTagName("lua")
+
+	type Limits struct {
+		Max   *big.Int   `lua:"max"`
+		Ratio *big.Rat   `lua:"ratio"`
+		Scale *big.Float `lua:"scale"`
+		Min   big.Int    `lua:"min"`
+	}
+	maxInt, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+	limits := Limits{
+		Max:   maxInt,
+		Ratio: big.NewRat(1, 3),
+		Scale: big.NewFloat(1.25),
+	}
+	limits.Min.SetInt64(-1)
+	lv, err := m.Encode(L, limits)
+	assert.NoError(err)
+	tbl := lv.(*lua.LTable)
+	assert.Equal(lua.LString("123456789012345678901234567890"), tbl.RawGetString("max"))
+	assert.Equal(lua.LString("1/3"), tbl.RawGetString("ratio"))
+	assert.Equal(lua.LString("1.25"), tbl.RawGetString("scale"))
+	assert.Equal(lua.LString("-1"), tbl.RawGetString("min"))
+
+	var decoded Limits
+	err = m.Map(lv, &decoded)
+	assert.NoError(err)
+	assert.Equal(0, maxInt.Cmp(decoded.Max))
+	assert.Equal(0, limits.Ratio.Cmp(decoded.Ratio))
+	assert.Equal(0, limits.Scale.Cmp(decoded.Scale))
+	assert.Equal(int64(-1), decoded.Min.Int64())
+}
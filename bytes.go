@@ -0,0 +1,107 @@
+package gluamapper
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+
+	"github.com/yuin/gopher-lua"
+)
+
+// decodeString decodes the string in the text encoding of the tag option "encoding",
+// which can be "hex" or "base64". The string is used as is if no encoding option.
+func decodeString(s string, opts tagOptions) ([]byte, error) {
+	switch encoding := opts["encoding"]; encoding {
+	case "":
+		return []byte(s), nil
+	case "hex":
+		return hex.DecodeString(s)
+	case "base64":
+		return base64.StdEncoding.DecodeString(s)
+	default:
+		return nil, fmt.Errorf("unknown encoding %q", encoding)
+	}
+}
+
+// encodeToString is the reverse of decodeString.
+func encodeToString(b []byte, opts tagOptions) (string, error) {
+	switch encoding := opts["encoding"]; encoding {
+	case "":
+		return string(b), nil
+	case "hex":
+		return hex.EncodeToString(b), nil
+	case "base64":
+		return base64.StdEncoding.EncodeToString(b), nil
+	default:
+		return "", fmt.Errorf("unknown encoding %q", encoding)
+	}
+}
+
+// mapLuaStringToGoBytes maps a Lua string into a byte slice.
+func mapLuaStringToGoBytes(s string, rv reflect.Value, opts tagOptions) error {
+	b, err := decodeString(s, opts)
+	if err != nil {
+		return err
+	}
+	rv.Set(reflect.MakeSlice(rv.Type(), len(b), len(b)))
+	for i, c := range b {
+		rv.Index(i).SetUint(uint64(c))
+	}
+	return nil
+}
+
+// mapLuaStringToGoRunes maps a Lua string into a rune slice.
+func mapLuaStringToGoRunes(s string, rv reflect.Value, opts tagOptions) error {
+	b, err := decodeString(s, opts)
+	if err != nil {
+		return err
+	}
+	runes := []rune(string(b))
+	rv.Set(reflect.MakeSlice(rv.Type(), len(runes), len(runes)))
+	for i, r := range runes {
+		rv.Index(i).SetInt(int64(r))
+	}
+	return nil
+}
+
+// mapLuaStringToGoByteArray maps a Lua string into a byte array of the same length.
+func mapLuaStringToGoByteArray(s string, rv reflect.Value, opts tagOptions) error {
+	b, err := decodeString(s, opts)
+	if err != nil {
+		return err
+	}
+	if len(b) != rv.Len() {
+		return fmt.Errorf("%s expected but got %d bytes", rv.Type(), len(b))
+	}
+	for i, c := range b {
+		rv.Index(i).SetUint(uint64(c))
+	}
+	return nil
+}
+
+// encodeBytes encodes a byte slice or a byte array into a Lua string.
+func encodeBytes(rv reflect.Value, opts tagOptions) (lua.LValue, error) {
+	b := make([]byte, rv.Len())
+	for i := range b {
+		b[i] = byte(rv.Index(i).Uint())
+	}
+	s, err := encodeToString(b, opts)
+	if err != nil {
+		return lua.LNil, err
+	}
+	return lua.LString(s), nil
+}
+
+// encodeRunes encodes a rune slice to a Lua string.
+func encodeRunes(rv reflect.Value, opts tagOptions) (lua.LValue, error) {
+	runes := make([]rune, rv.Len())
+	for i := range runes {
+		runes[i] = rune(rv.Index(i).Int())
+	}
+	s, err := encodeToString([]byte(string(runes)), opts)
+	if err != nil {
+		return lua.LNil, err
+	}
+	return lua.LString(s), nil
+}
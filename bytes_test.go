@@ -0,0 +1,94 @@
+package gluamapper
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yuin/gopher-lua"
+)
+
+func TestMapBytes(t *testing.T) {
+	var err error
+	assert := require.New(t)
+	L := lua.NewState()
+
+	var b []byte
+	err = L.DoString(`key = "\1\2"`)
+	assert.NoError(err)
+	err = Map(L.GetGlobal("key"), &b)
+	assert.NoError(err)
+	assert.Equal([]byte{1, 2}, b)
+	err = L.DoString(`key = {3, 4}`)
+	assert.NoError(err)
+	err = Map(L.GetGlobal("key"), &b)
+	assert.NoError(err)
+	assert.Equal([]byte{3, 4}, b)
+
+	var arr [2]byte
+	err = Map(lua.LString("ab"), &arr)
+	assert.NoError(err)
+	assert.Equal([2]byte{'a', 'b'}, arr)
+	err = Map(lua.LString("abc"), &arr)
+	assert.EqualError(err, "[2]uint8 expected but got 3 bytes")
+
+	var runes []rune
+	err = Map(lua.LString("中文"), &runes)
+	assert.NoError(err)
+	assert.Equal([]rune("中文"), runes)
+	runesLv, err := NewMapper().Encode(L, runes)
+	assert.NoError(err)
+	assert.Equal(lua.LString("中文"), runesLv)
+
+	var ints []int
+	err = Map(lua.LString("ab"), &ints)
+	assert.EqualError(err, "[]int expected but got Lua string")
+
+	type Keys struct {
+		Hex    []byte   `lua:"hex,encoding=hex"`
+		Base64 [3]byte  `lua:"base64,encoding=base64"`
+		Raw    []byte   `lua:"raw"`
+		Runes  []rune   `lua:"runes,encoding=hex"`
+		Hashes [][]byte `lua:"hashes,encoding=hex"`
+	}
+	err = L.DoString(`keys = {hex = "0102ff", base64 = "AQID", raw = "xyz", runes = "e4b8ad", hashes = {"01", "02"}}`)
+	assert.NoError(err)
+	var keys Keys
+	m := NewMapperWithTagName("lua")
+	err = m.Map(L.GetGlobal("keys"), &keys)
+	assert.NoError(err)
+	expected := Keys{
+		Hex:    []byte{1, 2, 255},
+		Base64: [3]byte{1, 2, 3},
+		Raw:    []byte("xyz"),
+		Runes:  []rune("中"),
+		Hashes: [][]byte{{1}, {2}},
+	}
+	assert.Equal(expected, keys)
+
+	lv, err := m.Encode(L, &keys)
+	assert.NoError(err)
+	tbl := lv.(*lua.LTable)
+	assert.Equal(lua.LString("0102ff"), tbl.RawGetString("hex"))
+	assert.Equal(lua.LString("AQID"), tbl.RawGetString("base64"))
+	assert.Equal(lua.LString("xyz"), tbl.RawGetString("raw"))
+	assert.Equal(lua.LString("e4b8ad"), tbl.RawGetString("runes"))
+	var decoded Keys
+	err = m.Map(lv, &decoded)
+	assert.NoError(err)
+	assert.Equal(expected, decoded)
+
+	err = L.DoString(`keys = {hex = "xyz"}`)
+	assert.NoError(err)
+	err = m.Map(L.GetGlobal("keys"), &keys)
+	assert.Error(err)
+
+	type Bad struct {
+		Key []byte `lua:"key,encoding=rot13"`
+	}
+	err = m.Map(L.GetGlobal("keys"), &Bad{})
+	assert.NoError(err)
+	err = L.DoString(`keys = {key = "abc"}`)
+	assert.NoError(err)
+	err = m.Map(L.GetGlobal("keys"), &Bad{})
+	assert.EqualError(err, `Key: unknown encoding "rot13"`)
+}
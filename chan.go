@@ -0,0 +1,108 @@
+package gluamapper
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	assert "github.com/arl/assertgo"
+	"github.com/yuin/gopher-lua"
+)
+
+var luaChanType = reflect.TypeOf((chan lua.LValue)(nil))
+
+// mapChan maps a Lua channel into a Go channel.
+//
+// If the Go channel type is assignable from chan lua.LValue, the Lua channel is used directly.
+// Otherwise a new Go channel with the same capacity is created, with an adapter goroutine:
+//
+// For <-chan T and chan T, the goroutine receives the elements from the Lua channel,
+// maps them into T and sends them to the Go channel.
+// The Go channel is closed when the Lua channel is closed.
+//
+// For chan<- T, the goroutine receives the elements from the Go channel,
+// encodes them into Lua values and sends them to the Lua channel.
+// The Lua channel is closed when the Go channel is closed.
+// The elements are encoded through m.Executor if it is set,
+// otherwise in a Lua state of the goroutine, and never in m.LState.
+//
+// A bidirectional chan T is fed by the Lua producers like <-chan T,
+// so the Go side must only receive from it, and must not close it.
+//
+// The elements which fail to convert are dropped, and the errors are passed to m.ChanErrorFunc.
+func (m *Mapper) mapChan(lv lua.LValue, rv reflect.Value) error {
+	assert.True(lv != lua.LNil)
+	assert.True(rv.Kind() == reflect.Chan)
+	luaCh, ok := lv.(lua.LChannel)
+	if !ok {
+		return newTypeError(lv, rv)
+	}
+	rvType := rv.Type()
+	if luaChanType.AssignableTo(rvType) {
+		rv.Set(reflect.ValueOf((chan lua.LValue)(luaCh)))
+		return nil
+	}
+
+	goCh := reflect.MakeChan(reflect.ChanOf(reflect.BothDir, rvType.Elem()), cap(luaCh))
+	if rvType.ChanDir() == reflect.SendDir {
+		go m.forwardGoChanToLua(goCh, luaCh)
+	} else {
+		go m.forwardLuaChanToGo(luaCh, goCh)
+	}
+	rv.Set(goCh.Convert(rvType))
+	return nil
+}
+
+func (m *Mapper) forwardLuaChanToGo(luaCh lua.LChannel, goCh reflect.Value) {
+	defer goCh.Close()
+	elemType := goCh.Type().Elem()
+	for lv := range luaCh {
+		elemPtr := reflect.New(elemType)
+		if err := m.MapValue(lv, elemPtr.Elem()); err != nil {
+			m.handleChanError(fmt.Errorf("chan: %w", err))
+			continue
+		}
+		goCh.Send(elemPtr.Elem())
+	}
+}
+
+func (m *Mapper) forwardGoChanToLua(goCh reflect.Value, luaCh lua.LChannel) {
+	defer close(luaCh)
+	var L *lua.LState
+	if m.Executor == nil {
+		L = lua.NewState(lua.Options{SkipOpenLibs: true})
+		defer L.Close()
+	}
+	for {
+		elem, ok := goCh.Recv()
+		if !ok {
+			return
+		}
+		lv, err := m.encodeChanElem(L, elem)
+		if err != nil {
+			m.handleChanError(fmt.Errorf("chan: %w", err))
+			continue
+		}
+		luaCh <- lv
+	}
+}
+
+// encodeChanElem encodes the element in L, or through m.Executor if L is nil.
+func (m *Mapper) encodeChanElem(L *lua.LState, elem reflect.Value) (lua.LValue, error) {
+	if L != nil {
+		return m.EncodeValue(L, elem)
+	}
+	var lv lua.LValue
+	err := m.Executor.Execute(context.Background(), func(L *lua.LState) error {
+		var err error
+		lv, err = m.EncodeValue(L, elem)
+		return err
+	})
+	return lv, err
+}
+
+func (m *Mapper) handleChanError(err error) {
+	if m.ChanErrorFunc != nil {
+		m.ChanErrorFunc(err)
+	}
+}
@@ -0,0 +1,88 @@
+package gluamapper
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yuin/gopher-lua"
+)
+
+func TestMapChan(t *testing.T) {
+	var err error
+	assert := require.New(t)
+
+	luaCh := make(chan lua.LValue, 3)
+	var raw chan lua.LValue
+	err = Map(lua.LChannel(luaCh), &raw)
+	assert.NoError(err)
+	assert.Equal(luaCh, raw)
+
+	var errs []error
+	m := NewMapper()
+	m.ChanErrorFunc = func(err error) { errs = append(errs, err) }
+	var ints <-chan int
+	err = m.Map(lua.LChannel(luaCh), &ints)
+	assert.NoError(err)
+	luaCh <- lua.LNumber(1)
+	luaCh <- lua.LString("abc")
+	luaCh <- lua.LNumber(2)
+	close(luaCh)
+	var received []int
+	for n := range ints {
+		received = append(received, n)
+	}
+	assert.Equal([]int{1, 2}, received)
+	assert.Equal(1, len(errs))
+	assert.EqualError(errs[0], "chan: int expected but got Lua string")
+
+	luaCh = make(chan lua.LValue)
+	var send chan<- []string
+	err = m.Map(lua.LChannel(luaCh), &send)
+	assert.NoError(err)
+	go func() {
+		send <- []string{"a", "b"}
+		close(send)
+	}()
+	lv := <-luaCh
+	assert.Equal(lua.LString("b"), lv.(*lua.LTable).RawGetInt(2))
+	_, ok := <-luaCh
+	assert.False(ok)
+
+	err = m.Map(lua.LNumber(1), &ints)
+	assert.EqualError(err, "<-chan int expected but got Lua number")
+
+	luaCh = make(chan lua.LValue, 2)
+	var both chan int
+	err = m.Map(lua.LChannel(luaCh), &both)
+	assert.NoError(err)
+	luaCh <- lua.LNumber(3)
+	luaCh <- lua.LNumber(4)
+	close(luaCh)
+	received = nil
+	for n := range both {
+		received = append(received, n)
+	}
+	assert.Equal([]int{3, 4}, received)
+}
+
+func TestMapSendChanWithExecutor(t *testing.T) {
+	var err error
+	assert := require.New(t)
+	executor := NewGoroutineExecutor(lua.NewState())
+	defer executor.Close()
+
+	m := NewMapper()
+	m.Executor = executor
+	luaCh := make(chan lua.LValue)
+	var send chan<- map[string]int
+	err = m.Map(lua.LChannel(luaCh), &send)
+	assert.NoError(err)
+	go func() {
+		send <- map[string]int{"a": 1}
+		close(send)
+	}()
+	lv := <-luaCh
+	assert.Equal(lua.LNumber(1), lv.(*lua.LTable).RawGetString("a"))
+	_, ok := <-luaCh
+	assert.False(ok)
+}
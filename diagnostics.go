@@ -0,0 +1,60 @@
+package gluamapper
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/yuin/gopher-lua"
+)
+
+// Warning is a problem found in mapping which is not an error,
+// such as the use of a deprecated key.
+type Warning struct {
+	// Field is the struct field, like "main.Config.ListenPort".
+	Field string
+	// Key is the Lua table key.
+	Key string
+	// Message is the message in the tag option, like "use listen_port".
+	Message string
+}
+
+func (w Warning) String() string {
+	return fmt.Sprintf("%s: deprecated key %q: %s", w.Field, w.Key, w.Message)
+}
+
+// Diagnostics records the warnings found in mapping.
+// It is goroutine-safe, because the Go funcs and channels created by the mapping
+// may record warnings later in other goroutines.
+type Diagnostics struct {
+	mtx      sync.Mutex
+	warnings []Warning
+}
+
+// Warnings returns a copy of the recorded warnings.
+func (d *Diagnostics) Warnings() []Warning {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	return append([]Warning(nil), d.warnings...)
+}
+
+func (d *Diagnostics) add(w Warning) {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	d.warnings = append(d.warnings, w)
+}
+
+// MapWithDiagnostics is like Map but also returns the diagnostics of mapping,
+// which records each use of a deprecated key.
+func (m *Mapper) MapWithDiagnostics(lv lua.LValue, output interface{}) (*Diagnostics, error) {
+	diagnostics := &Diagnostics{}
+	mapper := *m
+	mapper.diagnostics = diagnostics
+	err := mapper.Map(lv, output)
+	return diagnostics, err
+}
+
+func (m *Mapper) warn(w Warning) {
+	if m.diagnostics != nil {
+		m.diagnostics.add(w)
+	}
+}
@@ -0,0 +1,65 @@
+package gluamapper
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yuin/gopher-lua"
+)
+
+func TestMapAliasAndDeprecated(t *testing.T) {
+	var err error
+	assert := require.New(t)
+	L := lua.NewState()
+	m := NewMapperWithTagName("lua")
+
+	type Config struct {
+		ListenPort int    `lua:"listen_port,alias=port|bind_port,deprecated=use listen_port"`
+		Host       string `lua:"host,alias=addr"`
+		Legacy     bool   `lua:"legacy,deprecated=remove it"`
+	}
+	tests := []struct {
+		lua      string
+		config   Config
+		warnings []string
+		err      string
+	}{
+		{lua: `{listen_port = 80}`, config: Config{ListenPort: 80}},
+		{
+			lua:      `{port = 81, addr = "a"}`,
+			config:   Config{ListenPort: 81, Host: "a"},
+			warnings: []string{`gluamapper.Config.ListenPort: deprecated key "port": use listen_port`},
+		},
+		{
+			lua:      `{bind_port = 82, legacy = true}`,
+			config:   Config{ListenPort: 82, Legacy: true},
+			warnings: []string{`gluamapper.Config.ListenPort: deprecated key "bind_port": use listen_port`, `gluamapper.Config.Legacy: deprecated key "legacy": remove it`},
+		},
+		{lua: `{listen_port = 80, bind_port = 82}`, err: `ListenPort: conflicting keys "listen_port" and "bind_port"`},
+		{lua: `{port = 81, bind_port = 82}`, err: `ListenPort: conflicting keys "port" and "bind_port"`},
+	}
+	for _, test := range tests {
+		err = L.DoString("cfg = " + test.lua)
+		assert.NoError(err)
+		var cfg Config
+		diagnostics, err := m.MapWithDiagnostics(L.GetGlobal("cfg"), &cfg)
+		if test.err != "" {
+			assert.EqualError(err, test.err, test.lua)
+			continue
+		}
+		assert.NoError(err, test.lua)
+		assert.Equal(test.config, cfg, test.lua)
+		var warnings []string
+		for _, w := range diagnostics.Warnings() {
+			warnings = append(warnings, w.String())
+		}
+		assert.Equal(test.warnings, warnings, test.lua)
+	}
+
+	err = L.DoString(`cfg = {port = 81}`)
+	assert.NoError(err)
+	var cfg Config
+	err = m.Map(L.GetGlobal("cfg"), &cfg)
+	assert.NoError(err)
+	assert.Equal(81, cfg.ListenPort)
+}
@@ -8,6 +8,123 @@
 // You can use struct tags to look for a different key name in the Lua table.
 // See example Mapper (tagName)
 //
+// NewMapperWithTagNames("lua", "json") looks for the "lua" tag first,
+// and falls back to the "json" tag. A field tagged `json:"-"` is skipped.
+//
+// Mapper.NameFunc converts the field names without tags into the keys,
+// such as SnakeCase for "work_place", and Mapper.CaseInsensitive
+// matches the keys case-insensitively. See example Mapper (nameFunc)
+//
+// Tag Options
+//
+// The tag value may have options after the key name, separated by commas,
+// such as `lua:"timeout,unit=ms"`. The options apply to the field value,
+// and to its elements if the field is a pointer, a slice, an array or a map.
+//
+// The tag option "alias" lists the old keys of a renamed field, like
+// `lua:"listen_port,alias=port|bind_port,deprecated=use listen_port"`.
+// It is an error if more than one of the keys are set.
+// Mapper.MapWithDiagnostics records the use of the deprecated keys as warnings.
+//
+// Key Paths
+//
+// A dotted key path like `lua:"server.http.port"` maps a field from nested tables,
+// and encoding creates the intermediate tables.
+// Dots in a key are escaped by a backslash like `lua:"a\.b"`, or quoted like `lua:"'a.b'.c"`.
+//
+// The field with the tag option "remain", like `lua:",remain"`, receives the entries
+// which no other field consumes, and encoding writes them back.
+// A key path consumes only its last key, so with `lua:"server.host"`
+// the other entries of the table server are kept in the remain field.
+// It must be map[string]interface{}, map[string]lua.LValue or *lua.LTable.
+//
+// Positional Fields
+//
+// The tag option "pos" maps a field from an array index, like `lua:",pos=1"`,
+// and the field with both a name and a pos also accepts the named key.
+// A blank field "_" with the tag option "tuple", like `lua:",tuple"`,
+// makes the fields without a name positional in order.
+// So {10, 20} or {1, 100, step=5} maps to a struct, and encodes back to a compact array.
+// It is an error if the array has more elements than the positional fields,
+// unless the remain field is a *lua.LTable.
+//
+// Keyed Tables
+//
+// The struct field with the tag option "key", like `lua:"name,key"`,
+// receives the key of the entry when a keyed table like {api = {port = 80}}
+// is mapped to a map of the structs, or to a slice of the structs sorted by the keys.
+// Reversely, the tag option "keyby" maps an array of structs to a map
+// keyed by the struct field, like `lua:"users,keyby=ID"`,
+// and encodes the map back to the array sorted by the keys.
+// It is an error if the keys are duplicate.
+//
+// Sets
+//
+// A Lua set like {read = true, write = false} maps to map[string]struct{},
+// a sorted []string or a registered bit flag type, excluding the false entries.
+// A Lua array like {"read", "write"} maps to map[string]struct{} or map[string]bool.
+// map[string]struct{} is encoded to a Lua set.
+//
+// Shorthands
+//
+// A non-table value maps to the shorthand field of a struct, so `listen = 8080`
+// is short for `listen = {port = 8080}`. The shorthand field has the tag option
+// "shorthand", like `lua:"port,shorthand"`, or its name is returned by
+// the LuaShorthand interface of the struct.
+// A non-table value also maps to a slice of the single element,
+// so `upstream = "10.0.0.1"` is short for `upstream = {"10.0.0.1"}`.
+//
+// Arrays
+//
+// Mapper.HolePolicy selects to return an error, to stop, or to fill zero values
+// at the nil holes of the Lua arrays like {1, nil, 3}.
+// Mapper.SparseArrays allows the integer keys out of the sequence like {[5] = x},
+// and the 0-based arrays like {[0] = x, y}.
+//
+// Deferred Mapping
+//
+// A Raw field captures the Lua value and the settings of the Mapper,
+// and Raw.Decode maps the value later, once the Go type is known,
+// like json.RawMessage.
+//
+// Time
+//
+// time.Duration is mapped from a string like "1m30s", or a number in the unit
+// of the tag option "unit", such as "ms", "s" or "h".
+// time.Time is mapped from a string in the tag option "layout", RFC 3339 by default,
+// or a number of the time since the Unix epoch, in seconds by default.
+// *time.Location is mapped from an IANA time zone name.
+//
+// Units
+//
+// Number fields with the tag option "unit" also accept strings with units:
+// "unit=bytes" for byte sizes like "10MB" or "512KiB",
+// "unit=percent" for percentages like "75%",
+// and "unit=rate" for rates per second like "100/s".
+//
+// Bytes
+//
+// []byte, [N]byte and []rune are also mapped from Lua strings, and encoded to them.
+// The tag option "encoding" decodes the string as "hex" or "base64" text.
+//
+// Big Numbers
+//
+// big.Int, big.Float and big.Rat are mapped from decimal strings,
+// or from Lua numbers which they can represent exactly.
+//
+// Enums
+//
+// Mapper.RegisterEnum registers the names of an enum type,
+// which is then mapped from the names like "warn".
+// Mapper.RegisterFlags registers a bit flag type,
+// which is also mapped from an array of names like {"read", "write"}.
+//
+// Polymorphic Interfaces
+//
+// Mapper.RegisterType registers the concrete types of an interface type
+// by the values of a discriminator key, such as {type="s3", ...}.
+// See Mapper.DiscriminatorKey.
+//
 // Unexported fields
 //
 // Since unexported (private) struct fields cannot be set outside the package
@@ -0,0 +1,186 @@
+package gluamapper
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/yuin/gopher-lua"
+)
+
+var luaValueType = reflect.TypeOf((*lua.LValue)(nil)).Elem()
+
+// Encode encodes the Go value into a Lua value, which is the reverse of Map.
+// L is used to create Lua tables.
+//
+// Go bools, numbers and strings are encoded into Lua booleans, numbers and strings.
+// Slices, arrays, maps and structs are encoded into Lua tables,
+// using the same field names as Map.
+// Pointers and interfaces are encoded as the values they point to,
+// and nil values are encoded into Lua nil.
+// Byte slices and byte arrays are encoded into Lua strings,
+// in the text encoding of the tag option "encoding" if any.
+// time.Duration, time.Time and *time.Location are encoded into Lua strings.
+// big.Int, big.Float and big.Rat are encoded into exact Lua strings.
+// Registered enum and flag types are encoded into their names.
+// Values which are already Lua values are returned as is.
+// Other values, such as funcs and chans, are encoded into Lua user data.
+func (m *Mapper) Encode(L *lua.LState, input interface{}) (lua.LValue, error) {
+	return m.EncodeValue(L, reflect.ValueOf(input))
+}
+
+// EncodeValue encodes the Go value into a Lua value.
+func (m *Mapper) EncodeValue(L *lua.LState, rv reflect.Value) (lua.LValue, error) {
+	return m.encodeValue(L, rv, nil)
+}
+
+// encodeValue encodes the Go value into a Lua value with the struct field tag options.
+func (m *Mapper) encodeValue(L *lua.LState, rv reflect.Value, opts tagOptions) (lua.LValue, error) {
+	if !rv.IsValid() {
+		return lua.LNil, nil
+	}
+	if rv.Type().Implements(luaValueType) {
+		if canBeNil(rv) && rv.IsNil() {
+			return lua.LNil, nil
+		}
+		return rv.Interface().(lua.LValue), nil
+	}
+
+	if info := m.enums[rv.Type()]; info != nil {
+		if lv, ok := info.encode(L, rv); ok {
+			return lv, nil
+		}
+	}
+
+	switch rv.Type() {
+	case rawType:
+		return encodeRaw(rv), nil
+	case durationType:
+		return lua.LString(rv.Interface().(time.Duration).String()), nil
+	case timeType:
+		return lua.LString(rv.Interface().(time.Time).Format(time.RFC3339Nano)), nil
+	case locationPtrType:
+		if rv.IsNil() {
+			return lua.LNil, nil
+		}
+		return lua.LString(rv.Interface().(*time.Location).String()), nil
+	case bigIntType, bigFloatType, bigRatType:
+		return encodeBigNumber(rv), nil
+	}
+
+	switch rv.Kind() {
+	case reflect.Bool:
+		return lua.LBool(rv.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return lua.LNumber(rv.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return lua.LNumber(rv.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return lua.LNumber(rv.Float()), nil
+	case reflect.String:
+		return lua.LString(rv.String()), nil
+	case reflect.Interface, reflect.Ptr:
+		if rv.IsNil() {
+			return lua.LNil, nil
+		}
+		if info := m.variants[rv.Type()]; info != nil {
+			return m.encodeVariant(L, info, rv, opts)
+		}
+		return m.encodeValue(L, rv.Elem(), opts)
+	case reflect.Slice:
+		if rv.IsNil() {
+			return lua.LNil, nil
+		}
+		if rv.Type().Elem().Kind() == reflect.Int32 {
+			return encodeRunes(rv, opts)
+		}
+		fallthrough
+	case reflect.Array:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			return encodeBytes(rv, opts)
+		}
+		return m.encodeArray(L, rv, opts)
+	case reflect.Map:
+		if rv.IsNil() {
+			return lua.LNil, nil
+		}
+		if opts["keyby"] != "" {
+			return m.encodeMapByField(L, rv, opts)
+		}
+		return m.encodeMap(L, rv, opts)
+	case reflect.Struct:
+		return m.encodeStruct(L, rv)
+	}
+
+	// chan, func and others
+	ud := L.NewUserData()
+	ud.Value = rv.Interface()
+	return ud, nil
+}
+
+func (m *Mapper) encodeArray(L *lua.LState, rv reflect.Value, opts tagOptions) (lua.LValue, error) {
+	rvLen := rv.Len()
+	tbl := L.CreateTable(rvLen, 0)
+	for i := 0; i < rvLen; i++ {
+		lv, err := m.encodeValue(L, rv.Index(i), opts)
+		if err != nil {
+			return lua.LNil, fmt.Errorf("[%d]: %w", i, err)
+		}
+		tbl.RawSetInt(i+1, lv)
+	}
+	return tbl, nil
+}
+
+func (m *Mapper) encodeMap(L *lua.LState, rv reflect.Value, opts tagOptions) (lua.LValue, error) {
+	tbl := L.CreateTable(0, rv.Len())
+	iter := rv.MapRange()
+	for iter.Next() {
+		lKey, err := m.EncodeValue(L, iter.Key())
+		if err != nil {
+			return lua.LNil, fmt.Errorf("key %v: %w", iter.Key(), err)
+		}
+		if lKey == lua.LNil {
+			continue // nil can not be a Lua table key
+		}
+		if isEmptyStruct(rv.Type().Elem()) {
+			tbl.RawSet(lKey, lua.LTrue) // set
+			continue
+		}
+		lVal, err := m.encodeValue(L, iter.Value(), opts)
+		if err != nil {
+			return lua.LNil, fmt.Errorf("[%v]: %w", iter.Key(), err)
+		}
+		tbl.RawSet(lKey, lVal)
+	}
+	return tbl, nil
+}
+
+func (m *Mapper) encodeStruct(L *lua.LState, rv reflect.Value) (lua.LValue, error) {
+	tbl := L.NewTable()
+	rvType := rv.Type()
+	info := m.getStructInfo(rvType)
+	if info.err != nil {
+		return lua.LNil, info.err
+	}
+	for i := range info.fields {
+		fi := &info.fields[i]
+		lv, err := m.encodeValue(L, rv.Field(fi.index), fi.opts)
+		if err != nil {
+			return lua.LNil, fmt.Errorf("%s: %w", fi.name, err)
+		}
+		if fi.pos > 0 {
+			tbl.RawSetInt(fi.pos, lv)
+			continue
+		}
+		if err := setTableField(L, tbl, m.getFieldKey(fi), lv); err != nil {
+			return lua.LNil, fmt.Errorf("%s: %w", fi.name, err)
+		}
+	}
+
+	if info.remainIndex >= 0 {
+		if err := m.encodeRemain(L, tbl, rv.Field(info.remainIndex)); err != nil {
+			return lua.LNil, fmt.Errorf("%s: %w", rvType.Field(info.remainIndex).Name, err)
+		}
+	}
+	return tbl, nil
+}
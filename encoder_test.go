@@ -0,0 +1,58 @@
+package gluamapper
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yuin/gopher-lua"
+)
+
+func TestEncode(t *testing.T) {
+	assert := require.New(t)
+	L := lua.NewState()
+	m := NewMapperWithTagName("lua")
+
+	type Role struct {
+		Name string `lua:"name"`
+	}
+	type Person struct {
+		Name    string  `lua:"name"`
+		Age     int     `lua:"age"`
+		Roles   []*Role `lua:"roles"`
+		Nothing *Role   `lua:"nothing"`
+		private int
+	}
+	person := Person{
+		Name:  "Michel",
+		Age:   31,
+		Roles: []*Role{{Name: "Administrator"}, {Name: "Operator"}},
+	}
+	lv, err := m.Encode(L, &person)
+	assert.NoError(err)
+	tbl, ok := lv.(*lua.LTable)
+	assert.True(ok)
+	assert.Equal(lua.LString("Michel"), tbl.RawGetString("name"))
+	assert.Equal(lua.LNumber(31), tbl.RawGetString("age"))
+	assert.Equal(lua.LNil, tbl.RawGetString("nothing"))
+	assert.Equal(lua.LNil, tbl.RawGetString("private"))
+	assert.Equal(2, tbl.RawGetString("roles").(*lua.LTable).Len())
+
+	var output Person
+	err = m.Map(lv, &output)
+	assert.NoError(err)
+	assert.Equal(person, output)
+
+	lv, err = m.Encode(L, map[string]bool{"a": true})
+	assert.NoError(err)
+	assert.Equal(lua.LTrue, lv.(*lua.LTable).RawGetString("a"))
+	lv, err = m.Encode(L, nil)
+	assert.NoError(err)
+	assert.Equal(lua.LNil, lv)
+	lv, err = m.Encode(L, lua.LString("abc"))
+	assert.NoError(err)
+	assert.Equal(lua.LString("abc"), lv)
+	ch := make(chan int)
+	lv, err = m.Encode(L, ch)
+	assert.NoError(err)
+	assert.Equal(ch, lv.(*lua.LUserData).Value)
+}
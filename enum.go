@@ -0,0 +1,174 @@
+package gluamapper
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	assert "github.com/arl/assertgo"
+	"github.com/yuin/gopher-lua"
+)
+
+// enumInfo is the names of a registered enum or flag type.
+type enumInfo struct {
+	isFlags bool
+	values  map[string]reflect.Value
+	names   []string // sorted by value, then by name
+}
+
+// RegisterEnum registers the names of the enum type,
+// so that a Lua string of the name is mapped into the value,
+// and Encode encodes the value into its name.
+// The values of names must be convertible to enumType, such as untyped constants.
+// An unknown name is an error which lists the valid names.
+// Lua numbers are still mapped as usual.
+// RegisterEnum panics if a value can not be converted.
+// It must not be called concurrently with Map.
+func (m *Mapper) RegisterEnum(enumType reflect.Type, names map[string]interface{}) {
+	m.registerEnum(enumType, names, false)
+}
+
+// RegisterFlags is like RegisterEnum but for a bit flag type of an integer kind.
+// A Lua array of names like {"read", "write"} is mapped into the OR of the values,
+// and Encode encodes the value into an array of names.
+func (m *Mapper) RegisterFlags(flagsType reflect.Type, names map[string]interface{}) {
+	if !isIntegerKind(flagsType.Kind()) {
+		panic(fmt.Sprintf("flags type %s is not an integer kind", flagsType))
+	}
+	m.registerEnum(flagsType, names, true)
+}
+
+func (m *Mapper) registerEnum(enumType reflect.Type, names map[string]interface{}, isFlags bool) {
+	info := &enumInfo{
+		isFlags: isFlags,
+		values:  make(map[string]reflect.Value, len(names)),
+	}
+	for name, value := range names {
+		info.values[name] = reflect.ValueOf(value).Convert(enumType)
+		info.names = append(info.names, name)
+	}
+	sort.Slice(info.names, func(i, j int) bool {
+		vi, vj := info.values[info.names[i]], info.values[info.names[j]]
+		if isIntegerKind(enumType.Kind()) && toUint64(vi) != toUint64(vj) {
+			return toUint64(vi) < toUint64(vj)
+		}
+		return info.names[i] < info.names[j]
+	})
+
+	if m.enums == nil {
+		m.enums = make(map[reflect.Type]*enumInfo)
+	}
+	m.enums[enumType] = info
+}
+
+// mapEnum maps a Lua string, or a Lua array of strings for flags, into the registered type.
+func (m *Mapper) mapEnum(info *enumInfo, lv lua.LValue, rv reflect.Value) error {
+	switch v := lv.(type) {
+	case lua.LString:
+		value, err := info.lookup(string(v), rv.Type())
+		if err != nil {
+			return err
+		}
+		rv.Set(value)
+		return nil
+	case *lua.LTable:
+		if !info.isFlags {
+			break
+		}
+		if keys, isSet := getLuaSetKeys(v); isSet {
+			return m.mapLuaSetToGoFlags(info, keys, rv)
+		}
+		return m.mapLuaTableToGoFlags(info, v, rv)
+	}
+	return newTypeError(lv, rv)
+}
+
+func (m *Mapper) mapLuaTableToGoFlags(info *enumInfo, tbl *lua.LTable, rv reflect.Value) error {
+	assert.True(info.isFlags)
+	var bits uint64
+	for i := 1; i <= tbl.Len(); i++ {
+		lv := tbl.RawGetInt(i)
+		name, ok := lv.(lua.LString)
+		if !ok {
+			return fmt.Errorf("[%d]: %w", i, newTypeError(lv, rv))
+		}
+		value, err := info.lookup(string(name), rv.Type())
+		if err != nil {
+			return fmt.Errorf("[%d]: %w", i, err)
+		}
+		bits |= toUint64(value)
+	}
+	setUint64(rv, bits)
+	return nil
+}
+
+func (info *enumInfo) lookup(name string, enumType reflect.Type) (reflect.Value, error) {
+	if value, ok := info.values[name]; ok {
+		return value, nil
+	}
+	return reflect.Value{}, fmt.Errorf("unknown %s %q, valid choices: %s",
+		enumType, name, strings.Join(info.sortedNames(), ", "))
+}
+
+func (info *enumInfo) sortedNames() []string {
+	names := append([]string(nil), info.names...)
+	sort.Strings(names)
+	return names
+}
+
+// encode encodes the value into its name, or an array of names for flags.
+// ok is false if the value has no name, or has unnamed bits for flags.
+func (info *enumInfo) encode(L *lua.LState, rv reflect.Value) (lv lua.LValue, ok bool) {
+	if !info.isFlags {
+		for _, name := range info.names {
+			if info.values[name].Interface() == rv.Interface() {
+				return lua.LString(name), true
+			}
+		}
+		return lua.LNil, false
+	}
+
+	bits := toUint64(rv)
+	tbl := L.NewTable()
+	remain := bits
+	for _, name := range info.names {
+		flag := toUint64(info.values[name])
+		if flag != 0 && bits&flag == flag && remain&flag != 0 {
+			tbl.Append(lua.LString(name))
+			remain &^= flag
+		}
+	}
+	if remain != 0 {
+		return lua.LNil, false
+	}
+	return tbl, true
+}
+
+func isIntegerKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return true
+	}
+	return false
+}
+
+// toUint64 returns the bits of an integer value.
+func toUint64(rv reflect.Value) uint64 {
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return uint64(rv.Int())
+	}
+	return rv.Uint()
+}
+
+// setUint64 sets the bits into an integer value.
+func setUint64(rv reflect.Value, bits uint64) {
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		rv.SetInt(int64(bits))
+		return
+	}
+	rv.SetUint(bits)
+}
@@ -0,0 +1,134 @@
+package gluamapper
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yuin/gopher-lua"
+)
+
+type testLevel int
+
+const (
+	testLevelDebug testLevel = iota
+	testLevelInfo
+	testLevelWarn
+)
+
+type testPerm uint8
+
+const (
+	testPermRead testPerm = 1 << iota
+	testPermWrite
+	testPermExec
+)
+
+func newTestEnumMapper() *Mapper {
+	m := NewMapperWithTagName("lua")
+	m.RegisterEnum(reflect.TypeOf(testLevel(0)), map[string]interface{}{
+		"debug": testLevelDebug,
+		"info":  testLevelInfo,
+		"warn":  testLevelWarn,
+	})
+	m.RegisterFlags(reflect.TypeOf(testPerm(0)), map[string]interface{}{
+		"read":  testPermRead,
+		"write": testPermWrite,
+		"exec":  testPermExec,
+		"rw":    testPermRead | testPermWrite,
+	})
+	return m
+}
+
+func TestMapEnum(t *testing.T) {
+	var err error
+	assert := require.New(t)
+	m := newTestEnumMapper()
+
+	var level testLevel
+	err = m.Map(lua.LString("warn"), &level)
+	assert.NoError(err)
+	assert.Equal(testLevelWarn, level)
+	err = m.Map(lua.LNumber(1), &level)
+	assert.NoError(err)
+	assert.Equal(testLevelInfo, level)
+	err = m.Map(lua.LString("fatal"), &level)
+	assert.EqualError(err, `unknown gluamapper.testLevel "fatal", valid choices: debug, info, warn`)
+	err = m.Map(lua.LTrue, &level)
+	assert.EqualError(err, "gluamapper.testLevel expected but got Lua boolean")
+
+	var levels []testLevel
+	L := lua.NewState()
+	err = L.DoString(`levels = {"debug", "info"}`)
+	assert.NoError(err)
+	err = m.Map(L.GetGlobal("levels"), &levels)
+	assert.NoError(err)
+	assert.Equal([]testLevel{testLevelDebug, testLevelInfo}, levels)
+
+	err = Map(lua.LString("warn"), &level)
+	assert.EqualError(err, "gluamapper.testLevel expected but got Lua string")
+
+	assert.Panics(func() {
+		m.RegisterEnum(reflect.TypeOf(testLevel(0)), map[string]interface{}{"bad": "bad"})
+	})
+	assert.Panics(func() {
+		m.RegisterFlags(reflect.TypeOf(""), nil)
+	})
+}
+
+func TestMapFlags(t *testing.T) {
+	var err error
+	assert := require.New(t)
+	m := newTestEnumMapper()
+	L := lua.NewState()
+
+	var perm testPerm
+	err = L.DoString(`perm = {"read", "exec"}`)
+	assert.NoError(err)
+	err = m.Map(L.GetGlobal("perm"), &perm)
+	assert.NoError(err)
+	assert.Equal(testPermRead|testPermExec, perm)
+	err = m.Map(lua.LString("rw"), &perm)
+	assert.NoError(err)
+	assert.Equal(testPermRead|testPermWrite, perm)
+	err = L.DoString(`perm = {"read", "delete"}`)
+	assert.NoError(err)
+	err = m.Map(L.GetGlobal("perm"), &perm)
+	assert.EqualError(err, `[2]: unknown gluamapper.testPerm "delete", valid choices: exec, read, rw, write`)
+	err = L.DoString(`perm = {"read", 1}`)
+	assert.NoError(err)
+	err = m.Map(L.GetGlobal("perm"), &perm)
+	assert.EqualError(err, "[2]: gluamapper.testPerm expected but got Lua number")
+}
+
+func TestEncodeEnum(t *testing.T) {
+	assert := require.New(t)
+	m := newTestEnumMapper()
+	L := lua.NewState()
+
+	type Config struct {
+		Level testLevel `lua:"level"`
+		Perm  testPerm  `lua:"perm"`
+	}
+	lv, err := m.Encode(L, Config{Level: testLevelWarn, Perm: testPermRead | testPermWrite | testPermExec})
+	assert.NoError(err)
+	tbl := lv.(*lua.LTable)
+	assert.Equal(lua.LString("warn"), tbl.RawGetString("level"))
+	perm := tbl.RawGetString("perm").(*lua.LTable)
+	assert.Equal(3, perm.Len())
+	assert.Equal(lua.LString("read"), perm.RawGetInt(1))
+	assert.Equal(lua.LString("write"), perm.RawGetInt(2))
+	assert.Equal(lua.LString("exec"), perm.RawGetInt(3))
+
+	var decoded Config
+	err = m.Map(lv, &decoded)
+	assert.NoError(err)
+	assert.Equal(Config{Level: testLevelWarn, Perm: testPermRead | testPermWrite | testPermExec}, decoded)
+
+	lv, err = m.Encode(L, testLevel(100))
+	assert.NoError(err)
+	assert.Equal(lua.LNumber(100), lv)
+	lv, err = m.Encode(L, testPerm(0x80))
+	assert.NoError(err)
+	assert.Equal(lua.LNumber(0x80), lv)
+}
@@ -0,0 +1,140 @@
+package gluamapper
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/yuin/gopher-lua"
+)
+
+var (
+	ExecutorIsClosedError = errors.New("executor is closed")
+)
+
+// Executor serializes the access to a Lua state, which is not goroutine-safe.
+// Set Mapper.Executor to make the Go funcs mapped from Lua functions goroutine-safe.
+type Executor interface {
+	// Execute calls fn with the Lua state.
+	// ctx is set to the Lua state by LState.SetContext during the call,
+	// so the Lua code is stopped if ctx is canceled or times out.
+	// Execute must not be called from a Lua function running in the same executor.
+	Execute(ctx context.Context, fn func(L *lua.LState) error) error
+}
+
+// MutexExecutor is an Executor which guards the Lua state by a mutex.
+type MutexExecutor struct {
+	mtx sync.Mutex
+	L   *lua.LState
+}
+
+// NewMutexExecutor returns a new MutexExecutor of the Lua state.
+func NewMutexExecutor(L *lua.LState) *MutexExecutor {
+	return &MutexExecutor{L: L}
+}
+
+// Execute calls fn with the Lua state in the caller goroutine, holding the mutex.
+func (e *MutexExecutor) Execute(ctx context.Context, fn func(L *lua.LState) error) error {
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+	return executeWithContext(ctx, e.L, fn)
+}
+
+// GoroutineExecutor is an Executor which runs all the calls in a dedicated goroutine.
+type GoroutineExecutor struct {
+	L       *lua.LState
+	jobs    chan func()
+	closing chan struct{}
+	closed  chan struct{}
+	once    sync.Once
+}
+
+// NewGoroutineExecutor returns a new GoroutineExecutor of the Lua state
+// and starts its goroutine. Call Close to stop the goroutine.
+func NewGoroutineExecutor(L *lua.LState) *GoroutineExecutor {
+	e := &GoroutineExecutor{
+		L:       L,
+		jobs:    make(chan func()),
+		closing: make(chan struct{}),
+		closed:  make(chan struct{}),
+	}
+	go e.run()
+	return e
+}
+
+func (e *GoroutineExecutor) run() {
+	defer close(e.closed)
+	for {
+		select {
+		case job := <-e.jobs:
+			job()
+		case <-e.closing:
+			return
+		}
+	}
+}
+
+// Execute sends fn to the executor goroutine and waits for its result.
+// Execute returns ctx.Err() if ctx is done before fn is started,
+// or ExecutorIsClosedError if the executor is closed.
+func (e *GoroutineExecutor) Execute(ctx context.Context, fn func(L *lua.LState) error) error {
+	done := make(chan error, 1)
+	job := func() {
+		done <- executeWithContext(ctx, e.L, fn)
+	}
+
+	select {
+	case e.jobs <- job:
+		return <-done
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-e.closing:
+		return ExecutorIsClosedError
+	}
+}
+
+// Close stops the executor goroutine after the running call returns.
+func (e *GoroutineExecutor) Close() {
+	e.once.Do(func() { close(e.closing) })
+	<-e.closed
+}
+
+// executeWithContext calls fn with ctx set to L, and restores the old context of L after the call.
+func executeWithContext(ctx context.Context, L *lua.LState, fn func(L *lua.LState) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	oldCtx := L.Context()
+	L.SetContext(ctx)
+	defer func() {
+		if oldCtx == nil {
+			L.RemoveContext()
+		} else {
+			L.SetContext(oldCtx)
+		}
+	}()
+	return fn(L)
+}
+
+// CallFunction calls the Lua function with the arguments through the executor,
+// and returns all the Lua return values.
+// It makes the *lua.LFunction values from mapping safe to call in any goroutine.
+func CallFunction(ctx context.Context, executor Executor, fn *lua.LFunction, args ...lua.LValue) ([]lua.LValue, error) {
+	var results []lua.LValue
+	err := executor.Execute(ctx, func(L *lua.LState) error {
+		top := L.GetTop()
+		defer L.SetTop(top)
+		L.Push(fn)
+		for _, arg := range args {
+			L.Push(arg)
+		}
+		if err := L.PCall(len(args), lua.MultRet, nil); err != nil {
+			return err
+		}
+		for i := top + 1; i <= L.GetTop(); i++ {
+			results = append(results, L.Get(i))
+		}
+		return nil
+	})
+	return results, err
+}
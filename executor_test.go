@@ -0,0 +1,79 @@
+package gluamapper
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yuin/gopher-lua"
+)
+
+func testExecutor(t *testing.T, L *lua.LState, executor Executor) {
+	assert := require.New(t)
+	err := L.DoString(`
+		count = 0
+		function incr(n)
+			count = count + n
+			return count
+		end
+		function loop() while true do end end
+	`)
+	assert.NoError(err)
+
+	m := NewMapper()
+	m.Executor = executor
+	var incr func(int) (int, error)
+	err = m.Map(L.GetGlobal("incr"), &incr)
+	assert.NoError(err)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := incr(1); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	n, err := incr(0)
+	assert.NoError(err)
+	assert.Equal(100, n)
+
+	var loop func(context.Context) error
+	err = m.Map(L.GetGlobal("loop"), &loop)
+	assert.NoError(err)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err = loop(ctx)
+	assert.Error(err)
+	assert.Nil(L.Context())
+
+	m.CallTimeout = 10 * time.Millisecond
+	err = loop(context.Background())
+	assert.Error(err)
+
+	results, err := CallFunction(context.Background(), executor, L.GetGlobal("incr").(*lua.LFunction), lua.LNumber(1))
+	assert.NoError(err)
+	assert.Equal([]lua.LValue{lua.LNumber(101)}, results)
+}
+
+func TestMutexExecutor(t *testing.T) {
+	L := lua.NewState()
+	testExecutor(t, L, NewMutexExecutor(L))
+}
+
+func TestGoroutineExecutor(t *testing.T) {
+	assert := require.New(t)
+	L := lua.NewState()
+	executor := NewGoroutineExecutor(L)
+	testExecutor(t, L, executor)
+
+	executor.Close()
+	executor.Close()
+	err := executor.Execute(context.Background(), func(L *lua.LState) error { return nil })
+	assert.Equal(ExecutorIsClosedError, err)
+}
@@ -0,0 +1,127 @@
+package gluamapper
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	assert "github.com/arl/assertgo"
+	"github.com/yuin/gopher-lua"
+)
+
+var (
+	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+	contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+)
+
+// mapFunc maps a Lua function into a Go func which calls the Lua function
+// through m.Executor, or in m.LState if m.Executor is nil.
+//
+// The arguments of the Go func are encoded into Lua values by m.Encode,
+// and the Lua return values are mapped into the Go func results by m.Map.
+// If the last result of the Go func is an error,
+// it receives the Lua error or the mapping error,
+// otherwise the Go func panics with the error.
+func (m *Mapper) mapFunc(lv lua.LValue, rv reflect.Value) error {
+	assert.True(lv != lua.LNil)
+	assert.True(rv.Kind() == reflect.Func)
+	fn, ok := lv.(*lua.LFunction)
+	if !ok {
+		return newTypeError(lv, rv)
+	}
+	if m.LState == nil && m.Executor == nil {
+		return LStateIsNilError
+	}
+
+	fnType := rv.Type()
+	rv.Set(reflect.MakeFunc(fnType, func(args []reflect.Value) []reflect.Value {
+		return m.callLuaFunction(fn, fnType, args)
+	}))
+	return nil
+}
+
+func (m *Mapper) callLuaFunction(fn *lua.LFunction, fnType reflect.Type, args []reflect.Value) []reflect.Value {
+	results := make([]reflect.Value, fnType.NumOut())
+	for i := range results {
+		results[i] = reflect.New(fnType.Out(i)).Elem()
+	}
+
+	nret := len(results)
+	hasError := nret > 0 && fnType.Out(nret-1) == errorType
+	if hasError {
+		nret--
+	}
+	var ctx context.Context // nil if the Go func has no context
+	if fnType.NumIn() > 0 && fnType.In(0) == contextType {
+		ctx = context.Background()
+		if argCtx, ok := args[0].Interface().(context.Context); ok {
+			ctx = argCtx
+		}
+		args = args[1:]
+	}
+	err := m.execute(ctx, func(L *lua.LState) error {
+		return m.pcallLuaFunction(L, fn, fnType.IsVariadic(), args, results[:nret])
+	})
+	if err == nil {
+		return results
+	}
+	if !hasError {
+		panic(err)
+	}
+	results[nret].Set(reflect.ValueOf(err))
+	return results
+}
+
+// execute calls fn through m.Executor with the timeout,
+// or calls fn with m.LState directly if m.Executor is nil,
+// in which case ctx, if not nil, is set into m.LState during the call.
+func (m *Mapper) execute(ctx context.Context, fn func(L *lua.LState) error) error {
+	if m.Executor == nil {
+		if ctx == nil {
+			return fn(m.LState)
+		}
+		return executeWithContext(ctx, m.LState, fn)
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if m.CallTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, m.CallTimeout)
+		defer cancel()
+	}
+	return m.Executor.Execute(ctx, fn)
+}
+
+// pcallLuaFunction calls the Lua function in protected mode
+// and maps the Lua return values into results.
+func (m *Mapper) pcallLuaFunction(L *lua.LState, fn *lua.LFunction, isVariadic bool, args []reflect.Value, results []reflect.Value) error {
+	if isVariadic {
+		last := args[len(args)-1]
+		args = args[:len(args)-1]
+		for i := 0; i < last.Len(); i++ {
+			args = append(args, last.Index(i))
+		}
+	}
+
+	top := L.GetTop()
+	defer L.SetTop(top)
+	L.Push(fn)
+	for i, arg := range args {
+		lArg, err := m.EncodeValue(L, arg)
+		if err != nil {
+			return fmt.Errorf("argument %d: %w", i+1, err)
+		}
+		L.Push(lArg)
+	}
+	if err := L.PCall(len(args), len(results), nil); err != nil {
+		return err
+	}
+
+	for i, result := range results {
+		if err := m.MapValue(L.Get(top+1+i), result); err != nil {
+			return fmt.Errorf("result %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package gluamapper
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yuin/gopher-lua"
+)
+
+func TestMapFunc(t *testing.T) {
+	var err error
+	assert := require.New(t)
+	L := lua.NewState()
+	err = L.DoString(`
+		handler = {
+			OnEvent = function(name, payload)
+				if name == "bad" then
+					error("bad event")
+				end
+				return payload.n > 1, nil
+			end,
+		}
+		function add(a, b) return a + b end
+		function sum(...)
+			local s = 0
+			for _, n in ipairs({...}) do s = s + n end
+			return s
+		end
+		function str() return "abc" end
+	`)
+	assert.NoError(err)
+
+	type Handler struct {
+		OnEvent func(name string, payload map[string]interface{}) (bool, error)
+	}
+	var handler Handler
+	err = Map(L.GetGlobal("handler"), &handler)
+	assert.True(errors.Is(err, LStateIsNilError))
+
+	m := NewMapper()
+	m.LState = L
+	err = m.Map(L.GetGlobal("handler"), &handler)
+	assert.NoError(err)
+	ok, err := handler.OnEvent("good", map[string]interface{}{"n": 2})
+	assert.NoError(err)
+	assert.True(ok)
+	ok, err = handler.OnEvent("good", map[string]interface{}{"n": 1})
+	assert.NoError(err)
+	assert.False(ok)
+	_, err = handler.OnEvent("bad", nil)
+	assert.Error(err)
+	assert.True(strings.Contains(err.Error(), "bad event"))
+	assert.Equal(0, L.GetTop())
+
+	var add func(int, int) int
+	err = m.Map(L.GetGlobal("add"), &add)
+	assert.NoError(err)
+	assert.Equal(3, add(1, 2))
+
+	var sum func(...float64) float64
+	err = m.Map(L.GetGlobal("sum"), &sum)
+	assert.NoError(err)
+	assert.Equal(6.0, sum(1, 2, 3))
+	assert.Equal(0.0, sum())
+
+	var strToInt func() (int, error)
+	err = m.Map(L.GetGlobal("str"), &strToInt)
+	assert.NoError(err)
+	_, err = strToInt()
+	assert.EqualError(err, "result 1: int expected but got Lua string")
+	var strToIntPanic func() int
+	err = m.Map(L.GetGlobal("str"), &strToIntPanic)
+	assert.NoError(err)
+	assert.Panics(func() { strToIntPanic() })
+
+	err = m.Map(lua.LNumber(1), &add)
+	assert.EqualError(err, "func(int, int) int expected but got Lua number")
+	err = m.Map(lua.LNil, &add)
+	assert.NoError(err)
+	assert.Nil(add)
+}
+
+func TestMapFuncContextWithoutExecutor(t *testing.T) {
+	var err error
+	assert := require.New(t)
+	L := lua.NewState()
+	err = L.DoString(`function loop() while true do end end`)
+	assert.NoError(err)
+
+	m := NewMapper()
+	m.LState = L
+	var loop func(context.Context) error
+	err = m.Map(L.GetGlobal("loop"), &loop)
+	assert.NoError(err)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err = loop(ctx)
+	assert.Error(err)
+	assert.Nil(L.Context()) // restored
+}
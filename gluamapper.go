@@ -1,7 +1,10 @@
 package gluamapper
 
 import (
+	"fmt"
+	"math"
 	"reflect"
+	"strconv"
 
 	assert "github.com/arl/assertgo"
 	"github.com/yuin/gopher-lua"
@@ -11,8 +14,10 @@ import (
 // If output is not a pointer, Map returns OutputIsNotAPointerError.
 // If output is nil, Map returns OutputValueIsNilError.
 // If the Lua value is nil, the Go value will be set to its zero value.
-// Is the Lua value is a *lua.LUserData, the Go value will be set to the value of the LUserData
-// if they are the same type, or TypeError will be returned if they are not the same type.
+// If the Lua value is a *lua.LUserData, the Go value will be set to the value of the LUserData
+// if it is assignable, after dereferencing it or taking its address if necessary,
+// or TypeError will be returned if it can not be assigned.
+// See Mapper.ConvertUserData to also allow type conversions.
 //
 // Map will allocate maps, slices, and pointers as necessary,
 // with the following additional rules:
@@ -33,6 +38,14 @@ import (
 //	map[string]interface{}, for Lua tables
 //	nil for Lua nil
 //
+// To map a Lua value into a Go value of a Lua type, such as lua.LValue,
+// *lua.LTable, *lua.LFunction, *lua.LUserData or lua.LString,
+// Map stores the Lua value untouched, or returns TypeError if it is another Lua type.
+// Lua nil is stored as lua.LNil into lua.LValue.
+//
+// Mapper.NumberMode, AnyKeys, MixedTables and EmptyTableAsSlice change
+// the Go types of the numbers and the tables. See also ToGoValue.
+//
 // To map a Lua array into a slice, Map sets the slice len as Lua array len.
 // If the slice capacity is not large enough, Map resets the slice to a new one
 //
@@ -50,11 +63,24 @@ import (
 // if the Lua key can not be mapped into a Go key
 // or the Lua value can not be mapped into a Go value
 //
+// To map a Lua function into a func, Map creates a Go func
+// which calls the Lua function through Mapper.Executor, or in Mapper.LState
+// if Mapper.Executor is not set.
+// Map returns LStateIsNilError if neither of them is set.
+//
+// To map a Lua channel into a typed chan, <-chan or chan<-, Map creates a Go channel
+// and an adapter goroutine which converts the elements.
+// A typed bidirectional chan receives the elements from Lua like <-chan.
+// See Mapper.ChanErrorFunc for the conversion errors.
+//
 // If tag name is needed, please use NewMapperWithTagName(tagName).Map(...)
 func Map(lv lua.LValue, output interface{}) error {
-	return NewMapper().Map(lv, output)
+	return defaultMapper.Map(lv, output)
 }
 
+// defaultMapper is the Mapper of Map, which is never modified.
+var defaultMapper = NewMapper()
+
 func mapBool(lv lua.LValue, rv reflect.Value) error {
 	assert.True(rv.Kind() == reflect.Bool)
 	if b, ok := toBool(lv); ok {
@@ -68,234 +94,222 @@ func toBool(lv lua.LValue) (result bool, ok bool) {
 	switch v := lv.(type) {
 	case lua.LBool:
 		return bool(v), true
-	case *lua.LUserData:
-		result, ok = v.Value.(bool)
-		return result, ok
 	}
 	return false, false
 }
 
 func mapInt(lv lua.LValue, rv reflect.Value) error {
 	assert.True(rv.Kind() == reflect.Int)
-	switch v := lv.(type) {
-	case lua.LNumber:
-		rv.SetInt(int64(v))
+	if n, ok := lv.(lua.LNumber); ok {
+		rv.SetInt(int64(n))
 		return nil
-	case *lua.LUserData:
-		if n, ok := v.Value.(int); ok {
-			rv.SetInt(int64(n))
-			return nil
-		}
 	}
 	return newTypeError(lv, rv)
 }
 
 func mapInt8(lv lua.LValue, rv reflect.Value) error {
 	assert.True(rv.Kind() == reflect.Int8)
-	switch v := lv.(type) {
-	case lua.LNumber:
-		rv.SetInt(int64(v))
+	if n, ok := lv.(lua.LNumber); ok {
+		rv.SetInt(int64(n))
 		return nil
-	case *lua.LUserData:
-		if n, ok := v.Value.(int8); ok {
-			rv.SetInt(int64(n))
-			return nil
-		}
 	}
 	return newTypeError(lv, rv)
 }
 
 func mapInt16(lv lua.LValue, rv reflect.Value) error {
 	assert.True(rv.Kind() == reflect.Int16)
-	switch v := lv.(type) {
-	case lua.LNumber:
-		rv.SetInt(int64(v))
+	if n, ok := lv.(lua.LNumber); ok {
+		rv.SetInt(int64(n))
 		return nil
-	case *lua.LUserData:
-		if n, ok := v.Value.(int16); ok {
-			rv.SetInt(int64(n))
-			return nil
-		}
 	}
 	return newTypeError(lv, rv)
 }
 
 func mapInt32(lv lua.LValue, rv reflect.Value) error {
 	assert.True(rv.Kind() == reflect.Int32)
-	switch v := lv.(type) {
-	case lua.LNumber:
-		rv.SetInt(int64(v))
+	if n, ok := lv.(lua.LNumber); ok {
+		rv.SetInt(int64(n))
 		return nil
-	case *lua.LUserData:
-		if n, ok := v.Value.(int32); ok {
-			rv.SetInt(int64(n))
-			return nil
-		}
 	}
 	return newTypeError(lv, rv)
 }
 
 func mapInt64(lv lua.LValue, rv reflect.Value) error {
 	assert.True(rv.Kind() == reflect.Int64)
-	switch v := lv.(type) {
-	case lua.LNumber:
-		rv.SetInt(int64(v))
+	if n, ok := lv.(lua.LNumber); ok {
+		rv.SetInt(int64(n))
 		return nil
-	case *lua.LUserData:
-		if n, ok := v.Value.(int64); ok {
-			rv.SetInt(int64(n))
-			return nil
-		}
 	}
 	return newTypeError(lv, rv)
 }
 
 func mapUint(lv lua.LValue, rv reflect.Value) error {
 	assert.True(rv.Kind() == reflect.Uint)
-	switch v := lv.(type) {
-	case lua.LNumber:
-		rv.SetUint(uint64(v))
+	if n, ok := lv.(lua.LNumber); ok {
+		rv.SetUint(uint64(n))
 		return nil
-	case *lua.LUserData:
-		if n, ok := v.Value.(uint); ok {
-			rv.SetUint(uint64(n))
-			return nil
-		}
 	}
 	return newTypeError(lv, rv)
 }
 
 func mapUint8(lv lua.LValue, rv reflect.Value) error {
 	assert.True(rv.Kind() == reflect.Uint8)
-	switch v := lv.(type) {
-	case lua.LNumber:
-		rv.SetUint(uint64(v))
+	if n, ok := lv.(lua.LNumber); ok {
+		rv.SetUint(uint64(n))
 		return nil
-	case *lua.LUserData:
-		if n, ok := v.Value.(uint8); ok {
-			rv.SetUint(uint64(n))
-			return nil
-		}
 	}
 	return newTypeError(lv, rv)
 }
 
 func mapUint16(lv lua.LValue, rv reflect.Value) error {
 	assert.True(rv.Kind() == reflect.Uint16)
-	switch v := lv.(type) {
-	case lua.LNumber:
-		rv.SetUint(uint64(v))
+	if n, ok := lv.(lua.LNumber); ok {
+		rv.SetUint(uint64(n))
 		return nil
-	case *lua.LUserData:
-		if n, ok := v.Value.(uint16); ok {
-			rv.SetUint(uint64(n))
-			return nil
-		}
 	}
 	return newTypeError(lv, rv)
 }
 
 func mapUint32(lv lua.LValue, rv reflect.Value) error {
 	assert.True(rv.Kind() == reflect.Uint32)
-	switch v := lv.(type) {
-	case lua.LNumber:
-		rv.SetUint(uint64(v))
+	if n, ok := lv.(lua.LNumber); ok {
+		rv.SetUint(uint64(n))
 		return nil
-	case *lua.LUserData:
-		if n, ok := v.Value.(uint32); ok {
-			rv.SetUint(uint64(n))
-			return nil
-		}
 	}
 	return newTypeError(lv, rv)
 }
 
 func mapUint64(lv lua.LValue, rv reflect.Value) error {
 	assert.True(rv.Kind() == reflect.Uint64)
-	switch v := lv.(type) {
-	case lua.LNumber:
-		rv.SetUint(uint64(v))
+	if n, ok := lv.(lua.LNumber); ok {
+		rv.SetUint(uint64(n))
 		return nil
-	case *lua.LUserData:
-		if n, ok := v.Value.(uint64); ok {
-			rv.SetUint(uint64(n))
-			return nil
-		}
 	}
 	return newTypeError(lv, rv)
 }
 
 func mapFloat32(lv lua.LValue, rv reflect.Value) error {
 	assert.True(rv.Kind() == reflect.Float32)
-	switch v := lv.(type) {
-	case lua.LNumber:
-		rv.SetFloat(float64(v))
+	if n, ok := lv.(lua.LNumber); ok {
+		rv.SetFloat(float64(n))
 		return nil
-	case *lua.LUserData:
-		if f, ok := v.Value.(float32); ok {
-			rv.SetFloat(float64(f))
-			return nil
-		}
 	}
 	return newTypeError(lv, rv)
 }
 
 func mapFloat64(lv lua.LValue, rv reflect.Value) error {
 	assert.True(rv.Kind() == reflect.Float64)
+	if n, ok := lv.(lua.LNumber); ok {
+		rv.SetFloat(float64(n))
+		return nil
+	}
+	return newTypeError(lv, rv)
+}
+
+func mapUintptr(lv lua.LValue, rv reflect.Value) error {
+	assert.True(rv.Kind() == reflect.Uintptr)
+	n, ok := lv.(lua.LNumber)
+	if !ok {
+		return newTypeError(lv, rv)
+	}
+	if n < 0 || float64(n) >= math.Exp2(float64(rv.Type().Bits())) {
+		return fmt.Errorf("%v overflows %s", n, rv.Type())
+	}
+	rv.SetUint(uint64(n))
+	return nil
+}
+
+// mapComplex maps a Lua number, a string like "1+2i",
+// an array {re, im} or a table {re=re, im=im} into a complex number.
+func mapComplex(lv lua.LValue, rv reflect.Value) error {
+	assert.True(rv.Kind() == reflect.Complex64 || rv.Kind() == reflect.Complex128)
 	switch v := lv.(type) {
 	case lua.LNumber:
-		rv.SetFloat(float64(v))
+		rv.SetComplex(complex(float64(v), 0))
 		return nil
-	case *lua.LUserData:
-		if f, ok := v.Value.(float64); ok {
-			rv.SetFloat(f)
-			return nil
+	case lua.LString:
+		c, err := strconv.ParseComplex(string(v), rv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		rv.SetComplex(c)
+		return nil
+	case *lua.LTable:
+		lvRe, lvIm := v.RawGetInt(1), v.RawGetInt(2)
+		if lvRe == lua.LNil && lvIm == lua.LNil {
+			lvRe, lvIm = v.RawGetString("re"), v.RawGetString("im")
 		}
+		var re, im float64
+		if err := mapFloat64(lvRe, reflect.ValueOf(&re).Elem()); err != nil {
+			return fmt.Errorf("re: %w", err)
+		}
+		if lvIm != lua.LNil {
+			if err := mapFloat64(lvIm, reflect.ValueOf(&im).Elem()); err != nil {
+				return fmt.Errorf("im: %w", err)
+			}
+		}
+		rv.SetComplex(complex(re, im))
+		return nil
+	}
+	return newTypeError(lv, rv)
+}
+
+// mapLuaValue sets the Lua value untouched into the Go value of a Lua type,
+// such as lua.LValue, *lua.LTable or *lua.LFunction.
+func mapLuaValue(lv lua.LValue, rv reflect.Value) error {
+	assert.True(rv.Type().Implements(luaValueType))
+	lvVal := reflect.ValueOf(lv)
+	if lvVal.Type().AssignableTo(rv.Type()) {
+		rv.Set(lvVal)
+		return nil
 	}
 	return newTypeError(lv, rv)
 }
 
-// Always returns nil
-func mapInterface(lv lua.LValue, rv reflect.Value) error {
+// Returns TypeError if the converted value does not implement the interface.
+func (m *Mapper) mapInterface(lv lua.LValue, rv reflect.Value) error {
 	assert.True(lv != lua.LNil)
 	assert.True(rv.Kind() == reflect.Interface)
-	itf := toInterface(lv)
-	if itf != nil {
-		rv.Set(reflect.ValueOf(itf))
+	itf, err := m.toInterface(lv)
+	if err != nil {
+		return err
+	}
+	if itf == nil {
+		rv.Set(reflect.Zero(rv.Type())) // Set to nil
 		return nil
 	}
 
-	// can not call of reflect.Value.Set on zero Value
-	var i interface{} // nil
-	ri := reflect.ValueOf(&i).Elem()
-	rv.Set(ri) // Set to nil
-	return nil
+	itfVal := reflect.ValueOf(itf)
+	if itfVal.Type().AssignableTo(rv.Type()) {
+		rv.Set(itfVal)
+		return nil
+	}
+	return newTypeError(lv, rv)
 }
 
-func toInterface(lv lua.LValue) interface{} {
+func (m *Mapper) toInterface(lv lua.LValue) (interface{}, error) {
 	switch v := lv.(type) {
 	case *lua.LNilType:
-		return nil
+		return nil, nil
 	case lua.LBool:
-		return bool(v)
+		return bool(v), nil
 	case lua.LNumber:
-		return float64(v)
+		return m.numberToInterface(v), nil
 	case lua.LString:
-		return string(v)
+		return string(v), nil
 	case *lua.LFunction:
-		return v // keep as *LFunction
+		return v, nil // keep as *LFunction
 	case *lua.LUserData:
-		return v.Value // may be nil
+		return v.Value, nil // may be nil
 	case *lua.LState: // LTThread
-		return v // keep as *LState
+		return v, nil // keep as *LState
 	case *lua.LTable:
-		return luaTableToGoInterface(v)
+		return m.luaTableToGoInterface(v)
 	case lua.LChannel:
-		return (chan lua.LValue)(v)
+		return (chan lua.LValue)(v), nil
 	default:
-		return v // keep as v
+		return v, nil // keep as v
 	}
-	return nil
 }
 
 func mapString(lv lua.LValue, rv reflect.Value) error {
@@ -319,32 +333,80 @@ func toString(lv lua.LValue) (result string, ok bool) {
 	return "", false
 }
 
-func luaTableToGoMap(tbl *lua.LTable) map[string]interface{} {
+func (m *Mapper) luaTableToGoMap(tbl *lua.LTable) (map[string]interface{}, error) {
 	mp := make(map[string]interface{})
+	var err error
 	tbl.ForEach(func(lKey, lVal lua.LValue) {
-		if key, ok := toString(lKey); ok {
-			mp[key] = toInterface(lVal)
+		key, ok := toString(lKey)
+		if !ok || err != nil {
+			return
+		}
+		if mp[key], err = m.toInterface(lVal); err != nil {
+			err = fmt.Errorf("%s: %w", key, err)
+		}
+	})
+	return mp, err
+}
+
+// luaTableToGoAnyMap converts the Lua table to map[interface{}]interface{},
+// except the integer keys in [first, first+n).
+func (m *Mapper) luaTableToGoAnyMap(tbl *lua.LTable, first, n int) (map[interface{}]interface{}, error) {
+	mp := make(map[interface{}]interface{})
+	var err error
+	tbl.ForEach(func(lKey, lVal lua.LValue) {
+		if i, ok := lKey.(lua.LNumber); ok && float64(i) == float64(int(i)) && int(i) >= first && int(i) < first+n {
+			return // in the array
+		}
+		if err != nil {
+			return
+		}
+		key := m.keyToInterface(lKey)
+		if mp[key], err = m.toInterface(lVal); err != nil {
+			err = fmt.Errorf("%v: %w", key, err)
 		}
 	})
-	return mp
+	return mp, err
 }
 
-func luaTableToGoInterface(tbl *lua.LTable) interface{} {
+func (m *Mapper) luaTableToGoInterface(tbl *lua.LTable) (interface{}, error) {
 	assert.True(tbl != nil)
-	maxn := tbl.MaxN()
-	if maxn == 0 { // table -> map[string]interface{}
-		return luaTableToGoMap(tbl) // Only support string key
+	first, n, err := m.getLuaArrayBounds(tbl, tbl.MaxN())
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 { // table -> map
+		if m.EmptyTableAsSlice && isEmptyTable(tbl) {
+			return []interface{}{}, nil
+		}
+		if m.AnyKeys && hasNonStringKey(tbl) {
+			return m.luaTableToGoAnyMap(tbl, first, n)
+		}
+		return m.luaTableToGoMap(tbl) // Only support string key
 	}
 
 	// else: array -> []interface{}
-	slc := make([]interface{}, maxn, maxn)
-	for i := 0; i < maxn; i++ {
-		slc[i] = toInterface(tbl.RawGetInt(i + 1))
+	slc := make([]interface{}, n, n)
+	for i := 0; i < n; i++ {
+		if slc[i], err = m.toInterface(rawGetIndex(tbl, first+i)); err != nil {
+			return nil, fmt.Errorf("[%d]: %w", first+i, err)
+		}
 	}
-	return slc
+	if !m.MixedTables {
+		return slc, nil
+	}
+	mp, err := m.luaTableToGoAnyMap(tbl, first, n)
+	if err != nil || len(mp) == 0 {
+		return slc, err
+	}
+	return MixedTable{Array: slc, Map: mp}, nil
+}
+
+func isEmptyTable(tbl *lua.LTable) bool {
+	key, _ := tbl.Next(lua.LNil)
+	return key == lua.LNil
 }
 
-func mapLuaUserDataToGoValue(ud *lua.LUserData, rv reflect.Value) error {
+func (m *Mapper) mapLuaUserDataToGoValue(ud *lua.LUserData, rv reflect.Value) error {
 	assert.True(rv.IsValid()) // rv.Kind() != Invalid
 	udValue := ud.Value
 	if udValue == nil {
@@ -359,18 +421,56 @@ func mapLuaUserDataToGoValue(ud *lua.LUserData, rv reflect.Value) error {
 		}
 	}
 
-	// must be the same type
-	udValType := reflect.TypeOf(udValue)
-	if udValType == rv.Type() {
-		rv.Set(reflect.ValueOf(udValue))
+	if m.setGoValue(reflect.ValueOf(udValue), rv) {
 		return nil
 	}
 
-	return &TypeError{
-		goType:               rv.Type(),
-		luaType:              lua.LTUserData,
-		luaUserDataValueType: udValType,
+	return newTypeError(ud, rv)
+}
+
+// setGoValue sets rv to the Go value v and reports whether it succeeded.
+// v is set if it is assignable to rv, or convertible if m.ConvertUserData is true.
+// Otherwise setGoValue retries with *v if v is a pointer, and with &v if rv is a pointer,
+// so T, *T and the interfaces they implement are interchangeable.
+func (m *Mapper) setGoValue(v reflect.Value, rv reflect.Value) bool {
+	vType := v.Type()
+	rvType := rv.Type()
+	if vType.AssignableTo(rvType) {
+		rv.Set(v)
+		return true
+	}
+	if m.ConvertUserData && isConvertible(vType, rvType) {
+		rv.Set(v.Convert(rvType))
+		return true
+	}
+
+	if vType.Kind() == reflect.Ptr && !v.IsNil() && m.setGoValue(v.Elem(), rv) {
+		return true // *T -> T
+	}
+	if rvType.Kind() == reflect.Ptr {
+		elemPtr := reflect.New(rvType.Elem())
+		if m.setGoValue(v, elemPtr.Elem()) {
+			rv.Set(elemPtr) // T -> *T
+			return true
+		}
+	}
+	return false
+}
+
+// isConvertible reports whether a value of type from can be converted to type to.
+// Conversions from integers to strings and from slices to arrays are excluded,
+// because the former yields a rune and the latter panics on short slices.
+func isConvertible(from, to reflect.Type) bool {
+	if !from.ConvertibleTo(to) {
+		return false
+	}
+	if to.Kind() == reflect.String {
+		return from.Kind() == reflect.String || from.Kind() == reflect.Slice
+	}
+	if from.Kind() == reflect.Slice {
+		return to.Kind() == reflect.Slice || to.Kind() == reflect.String
 	}
+	return true
 }
 
 // canBeNil reports whether its argument v can be nil.
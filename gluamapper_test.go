@@ -1,6 +1,7 @@
 package gluamapper
 
 import (
+	"io"
 	"path/filepath"
 	"runtime"
 	"testing"
@@ -120,7 +121,7 @@ func TestMapBool(t *testing.T) {
 	goSt := struct{ a int }{a: 1234}
 	L.SetGlobal("goSt", luar.New(L, &goSt))
 	err = Map(L.GetGlobal("goSt"), &output)
-	assert.EqualError(err, "bool expected but got Lua user data of struct { a int }")
+	assert.EqualError(err, "bool expected but got Lua user data of *struct { a int }")
 	ud := L.NewUserData()
 	ud.Value = true
 	err = Map(ud, &output)
@@ -546,3 +547,179 @@ func TestMapInterface(t *testing.T) {
 	assert.NoError(err)
 	assert.Equal(arr, output)
 }
+
+type testMyInt int
+
+type testConn struct {
+	Addr string
+}
+
+func (c *testConn) Close() error { return nil }
+
+func TestMapUserData(t *testing.T) {
+	var err error
+	assert := require.New(t)
+	L := lua.NewState()
+	ud := L.NewUserData()
+
+	// *T -> T
+	ud.Value = &testConn{Addr: "a"}
+	var conn testConn
+	err = Map(ud, &conn)
+	assert.NoError(err)
+	assert.Equal("a", conn.Addr)
+
+	// T -> *T
+	ud.Value = testConn{Addr: "b"}
+	var pConn *testConn
+	err = Map(ud, &pConn)
+	assert.NoError(err)
+	assert.Equal("b", pConn.Addr)
+
+	// assignable to interface
+	p := &testConn{Addr: "c"}
+	ud.Value = p
+	var closer io.Closer
+	err = Map(ud, &closer)
+	assert.NoError(err)
+	assert.Equal(p, closer)
+	ud.Value = 123
+	err = Map(ud, &closer)
+	assert.EqualError(err, "io.Closer expected but got Lua user data of int")
+	ud.Value = (*testMyInt)(nil)
+	err = Map(ud, &closer)
+	assert.EqualError(err, "io.Closer expected but got Lua user data of nil *gluamapper.testMyInt")
+	var i int
+	err = Map(ud, &i)
+	assert.EqualError(err, "int expected but got Lua user data of nil *gluamapper.testMyInt")
+	err = Map(lua.LString("abc"), &closer)
+	assert.EqualError(err, "io.Closer expected but got Lua string")
+
+	// gopher-luar proxy
+	L.SetGlobal("conn", luar.New(L, p))
+	err = Map(L.GetGlobal("conn"), &conn)
+	assert.NoError(err)
+	assert.Equal("c", conn.Addr)
+
+	// convertible
+	ud.Value = testMyInt(123)
+	var n int
+	err = Map(ud, &n)
+	assert.EqualError(err, "int expected but got Lua user data of gluamapper.testMyInt")
+	m := NewMapper()
+	m.ConvertUserData = true
+	err = m.Map(ud, &n)
+	assert.NoError(err)
+	assert.Equal(123, n)
+	var pn *int
+	err = m.Map(ud, &pn)
+	assert.NoError(err)
+	assert.Equal(123, *pn)
+	var s string
+	err = m.Map(ud, &s)
+	assert.EqualError(err, "string expected but got Lua user data of gluamapper.testMyInt")
+	ud.Value = []int{1, 2}
+	var arr [3]int
+	err = m.Map(ud, &arr)
+	assert.EqualError(err, "[3]int expected but got Lua user data of []int")
+}
+
+func TestMapUintptr(t *testing.T) {
+	var err error
+	var output uintptr
+	assert := require.New(t)
+
+	err = Map(lua.LNumber(1234), &output)
+	assert.NoError(err)
+	assert.Equal(uintptr(1234), output)
+	err = Map(lua.LNumber(-1), &output)
+	assert.EqualError(err, "-1 overflows uintptr")
+	err = Map(lua.LNumber(1e30), &output)
+	assert.EqualError(err, "1e+30 overflows uintptr")
+	err = Map(lua.LTrue, &output)
+	assert.EqualError(err, "uintptr expected but got Lua boolean")
+}
+
+func TestMapComplex(t *testing.T) {
+	var err error
+	var output complex128
+	assert := require.New(t)
+	L := lua.NewState()
+
+	err = L.DoString(`
+		arr = {1, 2}
+		tbl = {re = 3, im = 4}
+		re = {5}
+		bad = {true, 1}
+	`)
+	assert.NoError(err)
+	err = Map(L.GetGlobal("arr"), &output)
+	assert.NoError(err)
+	assert.Equal(complex(1, 2), output)
+	err = Map(L.GetGlobal("tbl"), &output)
+	assert.NoError(err)
+	assert.Equal(complex(3, 4), output)
+	err = Map(L.GetGlobal("re"), &output)
+	assert.NoError(err)
+	assert.Equal(complex(5, 0), output)
+	err = Map(L.GetGlobal("bad"), &output)
+	assert.EqualError(err, "re: float64 expected but got Lua boolean")
+	err = Map(lua.LString("1+2i"), &output)
+	assert.NoError(err)
+	assert.Equal(complex(1, 2), output)
+	err = Map(lua.LString("abc"), &output)
+	assert.Error(err)
+	err = Map(lua.LNumber(6), &output)
+	assert.NoError(err)
+	assert.Equal(complex(6, 0), output)
+	err = Map(lua.LTrue, &output)
+	assert.EqualError(err, "complex128 expected but got Lua boolean")
+
+	var c64 complex64
+	err = Map(L.GetGlobal("arr"), &c64)
+	assert.NoError(err)
+	assert.Equal(complex64(complex(1, 2)), c64)
+}
+
+func TestMapLuaValue(t *testing.T) {
+	var err error
+	assert := require.New(t)
+	L := lua.NewState()
+	err = L.DoString(`
+		config = {
+			any = {1, 2},
+			sub = {name = "sub"},
+			handler = function() return 1 end,
+			data = nil,
+			name = "n",
+		}
+	`)
+	assert.NoError(err)
+	ud := L.NewUserData()
+	ud.Value = 1
+	config := L.GetGlobal("config").(*lua.LTable)
+	config.RawSetString("data", ud)
+
+	type Config struct {
+		Any     lua.LValue
+		Missing lua.LValue
+		Sub     *lua.LTable
+		Handler *lua.LFunction
+		Data    *lua.LUserData
+		Name    lua.LString
+	}
+	var c Config
+	m := NewMapperWithTagName("lua")
+	m.NameFunc = LowerCase
+	err = m.Map(config, &c)
+	assert.NoError(err)
+	assert.Same(config.RawGetString("any"), c.Any)
+	assert.Equal(lua.LNil, c.Missing)
+	assert.Same(config.RawGetString("sub"), c.Sub)
+	assert.Same(config.RawGetString("handler"), c.Handler)
+	assert.Same(ud, c.Data)
+	assert.Equal(lua.LString("n"), c.Name)
+
+	err = m.Map(lua.LString("s"), &c.Sub)
+	assert.EqualError(err, "*lua.LTable expected but got Lua string")
+}
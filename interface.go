@@ -0,0 +1,96 @@
+package gluamapper
+
+import (
+	"math"
+	"reflect"
+	"strconv"
+
+	"github.com/yuin/gopher-lua"
+)
+
+// NumberMode is the Go type of the Lua numbers converted to interface{}.
+type NumberMode int
+
+const (
+	// NumberFloat64 converts the Lua numbers to float64.
+	NumberFloat64 NumberMode = iota
+	// NumberInt64 converts the integral Lua numbers to int64, and the others to float64.
+	NumberInt64
+	// NumberLuaNumber converts the Lua numbers to LuaNumber.
+	NumberLuaNumber
+)
+
+// LuaNumber is a Lua number in the string literal, like json.Number.
+type LuaNumber string
+
+// String returns the literal of the number.
+func (n LuaNumber) String() string {
+	return string(n)
+}
+
+// Float64 returns the number as a float64.
+func (n LuaNumber) Float64() (float64, error) {
+	return strconv.ParseFloat(string(n), 64)
+}
+
+// Int64 returns the number as an int64.
+func (n LuaNumber) Int64() (int64, error) {
+	return strconv.ParseInt(string(n), 10, 64)
+}
+
+// MixedTable is a Lua table which has both the array part and the hash part,
+// like {1, 2, n = 2}, converted to interface{} when Mapper.MixedTables is true.
+type MixedTable struct {
+	Array []interface{}
+	// Map has the keys which are not in Array.
+	Map map[interface{}]interface{}
+}
+
+// ToGoValue converts the Lua value to a Go value in the rules of Map into interface{}.
+func ToGoValue(lv lua.LValue) interface{} {
+	v, _ := defaultMapper.ToGoValue(lv) // no error without HolePolicy
+	return v
+}
+
+// ToGoValue converts the Lua value to a Go value in the rules of Map into interface{},
+// with the options of the mapper, such as NumberMode, AnyKeys, MixedTables and EmptyTableAsSlice.
+func (m *Mapper) ToGoValue(lv lua.LValue) (interface{}, error) {
+	return m.toInterface(lv)
+}
+
+func (m *Mapper) numberToInterface(n lua.LNumber) interface{} {
+	f := float64(n)
+	switch m.NumberMode {
+	case NumberInt64:
+		if f == math.Trunc(f) && f >= math.MinInt64 && f < math.MaxInt64 {
+			return int64(f)
+		}
+	case NumberLuaNumber:
+		return LuaNumber(strconv.FormatFloat(f, 'f', -1, 64))
+	}
+	return f
+}
+
+// keyToInterface converts the Lua table key to a Go map key,
+// which keeps the Lua value if the converted value is not comparable.
+func (m *Mapper) keyToInterface(lKey lua.LValue) interface{} {
+	if _, ok := lKey.(*lua.LTable); ok {
+		return lKey
+	}
+	key, err := m.toInterface(lKey)
+	if err != nil || key != nil && !reflect.TypeOf(key).Comparable() {
+		return lKey
+	}
+	return key
+}
+
+// hasNonStringKey reports whether the Lua table has a key which is not a string.
+func hasNonStringKey(tbl *lua.LTable) bool {
+	found := false
+	tbl.ForEach(func(lKey, _ lua.LValue) {
+		if _, ok := toString(lKey); !ok {
+			found = true
+		}
+	})
+	return found
+}
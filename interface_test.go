@@ -0,0 +1,73 @@
+package gluamapper
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yuin/gopher-lua"
+)
+
+func TestToGoValue(t *testing.T) {
+	var err error
+	assert := require.New(t)
+	L := lua.NewState()
+	err = L.DoString(`
+		value = {
+			n = 3,
+			pi = 3.5,
+			list = {1, 2},
+			empty = {},
+			mixed = {"a", "b", x = 1},
+			keys = {[true] = "yes", [1.5] = "x", name = "n"},
+		}
+	`)
+	assert.NoError(err)
+	lv := L.GetGlobal("value")
+
+	assert.Equal(map[string]interface{}{
+		"n":     float64(3),
+		"pi":    3.5,
+		"list":  []interface{}{float64(1), float64(2)},
+		"empty": map[string]interface{}{},
+		"mixed": []interface{}{"a", "b"},
+		"keys":  map[string]interface{}{"name": "n"},
+	}, ToGoValue(lv))
+
+	m := NewMapper()
+	m.NumberMode = NumberInt64
+	m.AnyKeys = true
+	m.MixedTables = true
+	m.EmptyTableAsSlice = true
+	v, err := m.ToGoValue(lv)
+	assert.NoError(err)
+	assert.Equal(map[string]interface{}{
+		"n":     int64(3),
+		"pi":    3.5,
+		"list":  []interface{}{int64(1), int64(2)},
+		"empty": []interface{}{},
+		"mixed": MixedTable{
+			Array: []interface{}{"a", "b"},
+			Map:   map[interface{}]interface{}{"x": int64(1)},
+		},
+		"keys": map[interface{}]interface{}{true: "yes", 1.5: "x", "name": "n"},
+	}, v)
+
+	m.NumberMode = NumberLuaNumber
+	var n interface{}
+	assert.NoError(m.Map(lua.LNumber(3.5), &n))
+	assert.Equal(LuaNumber("3.5"), n)
+	f, err := n.(LuaNumber).Float64()
+	assert.NoError(err)
+	assert.Equal(3.5, f)
+	_, err = n.(LuaNumber).Int64()
+	assert.Error(err)
+	assert.NoError(m.Map(lua.LNumber(-7), &n))
+	i, err := n.(LuaNumber).Int64()
+	assert.NoError(err)
+	assert.Equal(int64(-7), i)
+	assert.NoError(m.Map(lua.LNumber(1000000), &n))
+	assert.Equal(LuaNumber("1000000"), n)
+	i, err = n.(LuaNumber).Int64()
+	assert.NoError(err)
+	assert.Equal(int64(1000000), i)
+}
@@ -0,0 +1,167 @@
+package gluamapper
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+
+	assert "github.com/arl/assertgo"
+	"github.com/yuin/gopher-lua"
+)
+
+// getKeyFieldIndex gets the index of the struct field with the tag option "key",
+// which receives the key of the keyed Lua table entry.
+// The type may be a pointer to the struct.
+// Returns -1 if no such field.
+func (m *Mapper) getKeyFieldIndex(t reflect.Type) int {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return -1
+	}
+	return m.getStructInfo(t).keyIndex
+}
+
+// injectKey sets the key of the keyed Lua table entry into the key field of the element.
+func (m *Mapper) injectKey(lKey lua.LValue, rvElem reflect.Value, keyIndex int) error {
+	if rvElem.Kind() == reflect.Ptr {
+		if rvElem.IsNil() {
+			return nil
+		}
+		rvElem = rvElem.Elem()
+	}
+	field := rvElem.Field(keyIndex)
+	if err := m.MapValue(lKey, field); err != nil {
+		return fmt.Errorf("%s: %w", rvElem.Type().Field(keyIndex).Name, err)
+	}
+	return nil
+}
+
+// mapKeyedLuaTableToGoSlice maps the keyed Lua table like {api = {...}, web = {...}}
+// to the slice of structs, sorted by the keys, which are set into the key fields.
+func (m *Mapper) mapKeyedLuaTableToGoSlice(tbl *lua.LTable, rv reflect.Value, keyIndex int, opts tagOptions) error {
+	assert.True(rv.Kind() == reflect.Slice)
+	var keys []string
+	tbl.ForEach(func(lKey, _ lua.LValue) {
+		if key, ok := lKey.(lua.LString); ok {
+			keys = append(keys, string(key))
+		}
+	})
+	if len(keys) == 0 {
+		rv.SetLen(0)
+		return nil
+	}
+	sort.Strings(keys)
+
+	slc := reflect.MakeSlice(rv.Type(), len(keys), len(keys))
+	for i, key := range keys {
+		rvElem := slc.Index(i)
+		err := m.mapValue(tbl.RawGetString(key), rvElem, opts)
+		if err == nil {
+			err = m.injectKey(lua.LString(key), rvElem, keyIndex)
+		}
+		if err != nil {
+			return fmt.Errorf("slice[%q]: %w", key, err)
+		}
+	}
+	rv.Set(slc)
+	return nil
+}
+
+// mapLuaTableToGoMapByField maps the Lua array of structs like {{id = "a"}, {id = "b"}}
+// to the map keyed by the struct field of the tag option "keyby", like "keyby=ID".
+// It is an error if the key field values are duplicate.
+func (m *Mapper) mapLuaTableToGoMapByField(tbl *lua.LTable, rv reflect.Value, fieldName string, opts tagOptions) error {
+	assert.True(rv.Kind() == reflect.Map)
+	mapType := rv.Type()
+	structType := mapType.Elem()
+	if structType.Kind() == reflect.Ptr {
+		structType = structType.Elem()
+	}
+	if structType.Kind() != reflect.Struct {
+		return fmt.Errorf("keyby %s: %s is not a struct", fieldName, structType)
+	}
+	field, ok := structType.FieldByName(fieldName)
+	if !ok || field.PkgPath != "" {
+		return fmt.Errorf("keyby %s: no such field in %s", fieldName, structType)
+	}
+	keyType := mapType.Key()
+	if !field.Type.Comparable() || !field.Type.AssignableTo(keyType) && !isConvertible(field.Type, keyType) {
+		return fmt.Errorf("keyby %s: %s can not be a key of %s", fieldName, field.Type, mapType)
+	}
+
+	mp := reflect.MakeMapWithSize(mapType, tbl.Len())
+	indexes := make(map[interface{}]int, tbl.Len())
+	for i := 1; i <= tbl.Len(); i++ {
+		rvElem := reflect.New(mapType.Elem()).Elem()
+		if err := m.mapValue(tbl.RawGetInt(i), rvElem, opts); err != nil {
+			return fmt.Errorf("[%d]: %w", i, err)
+		}
+		rvStruct := reflect.Indirect(rvElem)
+		if !rvStruct.IsValid() {
+			return fmt.Errorf("[%d]: nil can not be keyed by %s", i, fieldName)
+		}
+		rvField, err := fieldByIndex(rvStruct, field.Index)
+		if err != nil {
+			return fmt.Errorf("[%d]: keyby %s: %w", i, fieldName, err)
+		}
+		if rvField.Kind() == reflect.Interface && !rvField.IsNil() && !rvField.Elem().Type().Comparable() {
+			return fmt.Errorf("[%d]: keyby %s: %s can not be a key", i, fieldName, rvField.Elem().Type())
+		}
+		rvKey := rvField.Convert(keyType)
+		if j, ok := indexes[rvKey.Interface()]; ok {
+			return fmt.Errorf("[%d]: duplicate %s %v of [%d]", i, fieldName, rvKey, j)
+		}
+		indexes[rvKey.Interface()] = i
+		mp.SetMapIndex(rvKey, rvElem)
+	}
+	rv.Set(mp)
+	return nil
+}
+
+// fieldByIndex is like reflect.Value.FieldByIndex,
+// but returns an error instead of panicking on a nil embedded struct pointer.
+func fieldByIndex(rv reflect.Value, index []int) (reflect.Value, error) {
+	for i, x := range index {
+		if i > 0 && rv.Kind() == reflect.Ptr {
+			if rv.IsNil() {
+				return reflect.Value{}, fmt.Errorf("nil pointer to embedded struct %s", rv.Type().Elem())
+			}
+			rv = rv.Elem()
+		}
+		rv = rv.Field(x)
+	}
+	return rv, nil
+}
+
+// encodeMapByField encodes the map keyed by the struct field of the tag option "keyby"
+// to the Lua array of the values, sorted by the keys.
+func (m *Mapper) encodeMapByField(L *lua.LState, rv reflect.Value, opts tagOptions) (lua.LValue, error) {
+	keys := rv.MapKeys()
+	sort.Slice(keys, func(i, j int) bool { return lessValue(keys[i], keys[j]) })
+	tbl := L.CreateTable(len(keys), 0)
+	for i, key := range keys {
+		lv, err := m.encodeValue(L, rv.MapIndex(key), opts)
+		if err != nil {
+			return lua.LNil, fmt.Errorf("[%v]: %w", key, err)
+		}
+		tbl.RawSetInt(i+1, lv)
+	}
+	return tbl, nil
+}
+
+// lessValue compares the map keys for a deterministic order.
+func lessValue(a, b reflect.Value) bool {
+	switch a.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return a.Int() < b.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return a.Uint() < b.Uint()
+	case reflect.Float32, reflect.Float64:
+		return a.Float() < b.Float()
+	case reflect.String:
+		return a.String() < b.String()
+	}
+	return fmt.Sprint(a) < fmt.Sprint(b)
+}
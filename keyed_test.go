@@ -0,0 +1,157 @@
+package gluamapper
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yuin/gopher-lua"
+)
+
+func TestMapKeyedTable(t *testing.T) {
+	var err error
+	assert := require.New(t)
+	L := lua.NewState()
+	err = L.DoString(`
+		services = {
+			web = {port = 81},
+			api = {port = 80},
+		}
+		service_list = {{name = "db", port = 82}}
+		ids = {[1] = {port = 1}, [2] = {port = 2}}
+	`)
+	assert.NoError(err)
+
+	type Service struct {
+		Name string `lua:"name,key"`
+		Port int    `lua:"port"`
+	}
+	m := NewMapperWithTagName("lua")
+	var services []Service
+	err = m.Map(L.GetGlobal("services"), &services)
+	assert.NoError(err)
+	assert.Equal([]Service{{Name: "api", Port: 80}, {Name: "web", Port: 81}}, services)
+
+	err = m.Map(L.GetGlobal("service_list"), &services)
+	assert.NoError(err)
+	assert.Equal([]Service{{Name: "db", Port: 82}}, services)
+
+	var serviceMap map[string]*Service
+	err = m.Map(L.GetGlobal("services"), &serviceMap)
+	assert.NoError(err)
+	assert.Equal(map[string]*Service{
+		"api": {Name: "api", Port: 80},
+		"web": {Name: "web", Port: 81},
+	}, serviceMap)
+
+	type IDService struct {
+		ID   int `lua:",key"`
+		Port int `lua:"port"`
+	}
+	var idMap map[int]IDService
+	err = m.Map(L.GetGlobal("ids"), &idMap)
+	assert.NoError(err)
+	assert.Equal(map[int]IDService{1: {ID: 1, Port: 1}, 2: {ID: 2, Port: 2}}, idMap)
+
+	type BadService struct {
+		Name bool `lua:",key"`
+	}
+	var badServices []BadService
+	err = m.Map(L.GetGlobal("services"), &badServices)
+	assert.EqualError(err, `slice["api"]: Name: bool expected but got Lua string`)
+}
+
+func TestMapKeyBy(t *testing.T) {
+	var err error
+	assert := require.New(t)
+	L := lua.NewState()
+	err = L.DoString(`
+		acl = {
+			users = {{id = "b", admin = true}, {id = "a"}},
+			groups = {{id = 2}, {id = 1}},
+		}
+		duplicate = {users = {{id = "a"}, {id = "b"}, {id = "a"}}}
+	`)
+	assert.NoError(err)
+
+	type User struct {
+		ID    string `lua:"id"`
+		Admin bool   `lua:"admin"`
+	}
+	type GroupID int
+	type Group struct {
+		ID int `lua:"id"`
+	}
+	type ACL struct {
+		Users  map[string]User    `lua:"users,keyby=ID"`
+		Groups map[GroupID]*Group `lua:"groups,keyby=ID"`
+	}
+	m := NewMapperWithTagName("lua")
+	var acl ACL
+	err = m.Map(L.GetGlobal("acl"), &acl)
+	assert.NoError(err)
+	assert.Equal(ACL{
+		Users:  map[string]User{"a": {ID: "a"}, "b": {ID: "b", Admin: true}},
+		Groups: map[GroupID]*Group{1: {ID: 1}, 2: {ID: 2}},
+	}, acl)
+
+	err = m.Map(L.GetGlobal("duplicate"), &acl)
+	assert.EqualError(err, "Users: [3]: duplicate ID a of [1]")
+
+	type BadACL struct {
+		Users map[string]User `lua:"users,keyby=Name"`
+	}
+	err = m.Map(L.GetGlobal("acl"), &BadACL{})
+	assert.EqualError(err, "Users: keyby Name: no such field in gluamapper.User")
+
+	type IntKeyACL struct {
+		Groups map[string]*Group `lua:"groups,keyby=ID"`
+	}
+	err = m.Map(L.GetGlobal("acl"), &IntKeyACL{})
+	assert.EqualError(err, "Groups: keyby ID: int can not be a key of map[string]*gluamapper.Group")
+
+	type Embedded struct {
+		ID string `lua:"id"`
+	}
+	type EmbeddedUser struct {
+		*Embedded
+		Name string `lua:"name"`
+	}
+	type EmbeddedACL struct {
+		Users map[string]EmbeddedUser `lua:"users,keyby=ID"`
+	}
+	err = L.DoString(`embedded = {users = {{name = "a"}}}`)
+	assert.NoError(err)
+	err = m.Map(L.GetGlobal("embedded"), &EmbeddedACL{})
+	assert.EqualError(err, "Users: [1]: keyby ID: nil pointer to embedded struct gluamapper.Embedded")
+
+	type Tagged struct {
+		Tags []string `lua:"tags"`
+	}
+	type TaggedSet struct {
+		Items map[interface{}]Tagged `lua:"items,keyby=Tags"`
+	}
+	err = L.DoString(`tagged = {items = {{tags = {"a"}}}}`)
+	assert.NoError(err)
+	err = m.Map(L.GetGlobal("tagged"), &TaggedSet{})
+	assert.EqualError(err, "Items: keyby Tags: []string can not be a key of map[interface {}]gluamapper.Tagged")
+
+	type Valued struct {
+		Value interface{} `lua:"value"`
+	}
+	type ValuedSet struct {
+		Items map[interface{}]Valued `lua:"items,keyby=Value"`
+	}
+	err = L.DoString(`valued = {items = {{value = "a"}, {value = {1}}}}`)
+	assert.NoError(err)
+	err = m.Map(L.GetGlobal("valued"), &ValuedSet{})
+	assert.EqualError(err, "Items: [2]: keyby Value: []interface {} can not be a key")
+
+	lv, err := m.Encode(L, acl)
+	assert.NoError(err)
+	groups := lv.(*lua.LTable).RawGetString("groups").(*lua.LTable)
+	assert.Equal(2, groups.Len())
+	assert.Equal(lua.LNumber(1), groups.RawGetInt(1).(*lua.LTable).RawGetString("id"))
+	var decoded ACL
+	assert.NoError(m.Map(lv, &decoded))
+	assert.Equal(acl, decoded)
+}
@@ -0,0 +1,75 @@
+package gluamapper
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/yuin/gopher-lua"
+)
+
+// splitKeyPath splits a dotted key path like "server.http.port" into keys.
+// A dot is a part of the key if it is escaped by a backslash like `a\.b`,
+// or quoted by single quotes like "'a.b'".
+func splitKeyPath(path string) []string {
+	if !strings.ContainsAny(path, `.\'`) {
+		return []string{path}
+	}
+
+	var keys []string
+	var key strings.Builder
+	quoted := false
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+		switch {
+		case c == '\\' && i+1 < len(path):
+			i++
+			key.WriteByte(path[i])
+		case c == '\'':
+			quoted = !quoted
+		case c == '.' && !quoted:
+			keys = append(keys, key.String())
+			key.Reset()
+		default:
+			key.WriteByte(c)
+		}
+	}
+	return append(keys, key.String())
+}
+
+// getTablePath gets the value of the key path from the nested Lua tables.
+// Returns Lua nil if any table in the path is absent.
+func (m *Mapper) getTablePath(tbl *lua.LTable, path []string) (lua.LValue, error) {
+	for i, key := range path {
+		lv, err := m.getTableKey(tbl, key)
+		if err != nil || i == len(path)-1 || lv == lua.LNil {
+			return lv, err
+		}
+		var ok bool
+		if tbl, ok = lv.(*lua.LTable); !ok {
+			return lua.LNil, fmt.Errorf("%q expected a Lua table but got Lua %s", key, lv.Type())
+		}
+	}
+	return lua.LNil, nil // unreachable
+}
+
+// setTablePath sets the value of the key path into the nested Lua tables,
+// creating the absent tables. Lua nil is not set, and creates no table.
+func setTablePath(L *lua.LState, tbl *lua.LTable, path []string, lv lua.LValue) error {
+	if lv == lua.LNil {
+		return nil
+	}
+	last := len(path) - 1
+	for _, key := range path[:last] {
+		child := tbl.RawGetString(key)
+		if child == lua.LNil {
+			child = L.NewTable()
+			tbl.RawSetString(key, child)
+		}
+		var ok bool
+		if tbl, ok = child.(*lua.LTable); !ok {
+			return fmt.Errorf("%q expected a Lua table but got Lua %s", key, child.Type())
+		}
+	}
+	tbl.RawSetString(path[last], lv)
+	return nil
+}
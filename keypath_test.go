@@ -0,0 +1,64 @@
+package gluamapper
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yuin/gopher-lua"
+)
+
+func TestSplitKeyPath(t *testing.T) {
+	assert := require.New(t)
+	assert.Equal([]string{"port"}, splitKeyPath("port"))
+	assert.Equal([]string{"server", "http", "port"}, splitKeyPath("server.http.port"))
+	assert.Equal([]string{"a.b", "c"}, splitKeyPath(`a\.b.c`))
+	assert.Equal([]string{"a.b", "c"}, splitKeyPath("'a.b'.c"))
+	assert.Equal([]string{`a\b`}, splitKeyPath(`a\\b`))
+	assert.Equal([]string{"", "a"}, splitKeyPath(".a"))
+}
+
+func TestMapKeyPath(t *testing.T) {
+	var err error
+	assert := require.New(t)
+	L := lua.NewState()
+	err = L.DoString(`
+		config = {
+			server = {http = {port = 8080}},
+			["a.b"] = {c = "dotted"},
+			name = "x",
+		}
+	`)
+	assert.NoError(err)
+
+	type Config struct {
+		Port   int    `lua:"server.http.port"`
+		Host   string `lua:"server.http.host"`
+		Dotted string `lua:"'a.b'.c"`
+		Absent int    `lua:"client.timeout"`
+	}
+	m := NewMapperWithTagName("lua")
+	var config Config
+	err = m.Map(L.GetGlobal("config"), &config)
+	assert.NoError(err)
+	assert.Equal(Config{Port: 8080, Dotted: "dotted"}, config)
+
+	type BadConfig struct {
+		Name string `lua:"name.first"`
+	}
+	err = m.Map(L.GetGlobal("config"), &BadConfig{})
+	assert.EqualError(err, `Name: "name" expected a Lua table but got Lua string`)
+
+	lv, err := m.Encode(L, Config{Port: 80, Dotted: "d"})
+	assert.NoError(err)
+	tbl := lv.(*lua.LTable)
+	http := tbl.RawGetString("server").(*lua.LTable).RawGetString("http").(*lua.LTable)
+	assert.Equal(lua.LNumber(80), http.RawGetString("port"))
+	assert.Equal(lua.LString(""), http.RawGetString("host"))
+	assert.Equal(lua.LString("d"), tbl.RawGetString("a.b").(*lua.LTable).RawGetString("c"))
+	assert.Equal(lua.LNumber(0), tbl.RawGetString("client").(*lua.LTable).RawGetString("timeout"))
+
+	var decoded Config
+	err = m.Map(lv, &decoded)
+	assert.NoError(err)
+	assert.Equal(Config{Port: 80, Dotted: "d"}, decoded)
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"reflect"
 	"strings"
+	"time"
 
 	assert "github.com/arl/assertgo"
 	"github.com/yuin/gopher-lua"
@@ -12,12 +13,94 @@ import (
 
 var (
 	OutputValueIsNilError = errors.New("output value is nil")
+	LStateIsNilError      = errors.New("LState is nil")
 )
 
 // Mapper maps a Lua table to a Go struct pointer.
 type Mapper struct {
 	// A struct tag name for Lua table keys.
 	TagName string
+
+	// TagNames is the fallback tag names after TagName, in order.
+	// The first tag which has a key name is used, with its options,
+	// and with the options of the tags before it, like "remain" in `lua:",remain" json:"rest"`.
+	// The field is skipped if that tag is "-", like `json:"-"`.
+	TagNames []string
+
+	// NameFunc, if set, converts the struct field names into Lua table keys,
+	// for the fields without a key name in the tag, e.g. SnakeCase.
+	NameFunc NameFunc
+
+	// CaseInsensitive makes a struct field match a Lua table key case-insensitively,
+	// if no key matches exactly. It is an error if more than one key matches.
+	CaseInsensitive bool
+
+	// ConvertUserData allows the value of a Lua user data to be converted
+	// to the Go type if it is not assignable, e.g. MyInt to int.
+	// See reflect.Type.ConvertibleTo.
+	ConvertUserData bool
+
+	// LState is the Lua state to call Lua functions which are mapped into Go funcs.
+	// Mapping a Lua function into a Go func returns LStateIsNilError
+	// if both LState and Executor are nil.
+	LState *lua.LState
+
+	// Executor, if set, is used instead of LState to call Lua functions
+	// which are mapped into Go funcs, so that the Go funcs are goroutine-safe.
+	// If the first parameter of the Go func is a context.Context,
+	// it is passed to the Executor, or set into LState during the call
+	// if Executor is nil, and is not passed to the Lua function.
+	Executor Executor
+
+	// CallTimeout, if positive, is the timeout of the calls through Executor.
+	CallTimeout time.Duration
+
+	// ChanErrorFunc, if set, receives the errors of the elements
+	// which fail to convert between a Lua channel and a typed Go channel.
+	// It is called in the adapter goroutine of the channel.
+	ChanErrorFunc func(err error)
+
+	// DurationUnit is the unit of Lua numbers mapped into time.Duration,
+	// unless the field has a tag option like "unit=ms".
+	// Nanosecond is used if it is 0.
+	DurationUnit time.Duration
+
+	// HolePolicy is the policy of the nil holes in the Lua arrays like {1, nil, 3}.
+	HolePolicy HolePolicy
+
+	// SparseArrays allows the arrays to have the integer keys out of the sequence,
+	// like {[5] = x}, and to be 0-based, like {[0] = x, y}.
+	// The holes are filled with the zero values unless HolePolicy is HoleError or HoleStop.
+	// It is an error if an array of more than 1024 elements has less than half of them set,
+	// or if an integer key is negative or out of the int range.
+	SparseArrays bool
+
+	// NumberMode is the Go type of the Lua numbers mapped into interface{}.
+	// float64 is used by default.
+	NumberMode NumberMode
+
+	// AnyKeys maps the Lua tables which have non-string keys into interface{}
+	// as map[interface{}]interface{}, instead of dropping the non-string keys.
+	AnyKeys bool
+
+	// MixedTables maps the Lua tables which have both the array part and the hash part
+	// into interface{} as MixedTable, instead of dropping the hash part.
+	MixedTables bool
+
+	// EmptyTableAsSlice maps the empty Lua table into interface{}
+	// as an empty []interface{}, instead of an empty map[string]interface{}.
+	EmptyTableAsSlice bool
+
+	// DiscriminatorKey is the key of the Lua table to select the concrete type
+	// registered by RegisterType. It is "type" if empty.
+	DiscriminatorKey string
+
+	// enums is the registered enum and flag types.
+	enums map[reflect.Type]*enumInfo
+	// variants is the registered concrete types of interface types.
+	variants map[reflect.Type]*variantInfo
+	// diagnostics, if not nil, records the warnings. See MapWithDiagnostics.
+	diagnostics *Diagnostics
 }
 
 // NewMapper returns a new mapper.
@@ -32,6 +115,14 @@ func NewMapperWithTagName(tagName string) *Mapper {
 	}
 }
 
+// NewMapperWithTagNames returns a new mapper with the tag names in the fallback order,
+// e.g. NewMapperWithTagNames("lua", "json").
+func NewMapperWithTagNames(tagNames ...string) *Mapper {
+	return &Mapper{
+		TagNames: tagNames,
+	}
+}
+
 // Map maps the Lua value to the given Go pointer.
 func (m *Mapper) Map(lv lua.LValue, output interface{}) error {
 	rv := reflect.ValueOf(output)
@@ -43,24 +134,82 @@ func (m *Mapper) Map(lv lua.LValue, output interface{}) error {
 
 // MapValue maps the Lua value to Go value.
 func (m *Mapper) MapValue(lv lua.LValue, rv reflect.Value) error {
+	return m.mapValue(lv, rv, nil)
+}
+
+// mapValue maps the Lua value to Go value with the struct field tag options.
+func (m *Mapper) mapValue(lv lua.LValue, rv reflect.Value, opts tagOptions) error {
 	if lv != lua.LNil {
-		return m.mapNonNilValue(lv, rv)
+		return m.mapNonNilValue(lv, rv, opts)
 	}
 
 	// do not call rv.Type() if rv is zero Value
-	if rv.IsValid() {
-		rv.Set(reflect.Zero(rv.Type()))
+	if !rv.IsValid() {
+		return OutputValueIsNilError
+	}
+	rvType := rv.Type()
+	if rvType == luaValueType {
+		rv.Set(reflect.ValueOf(lv)) // keep Lua nil
 		return nil
 	}
-	return OutputValueIsNilError
+	if rvType == rawType {
+		return m.mapRaw(lv, rv)
+	}
+	if !rv.IsZero() {
+		rv.Set(reflect.Zero(rvType))
+	}
+	return nil
 }
 
-func (m *Mapper) mapNonNilValue(lv lua.LValue, rv reflect.Value) error {
+func (m *Mapper) mapNonNilValue(lv lua.LValue, rv reflect.Value, opts tagOptions) error {
 	assert.True(lv != lua.LNil) // lv is not *lua.LNilType
-	TBI := errors.New("to be implemented")
-	switch rv.Kind() {
-	case reflect.Invalid:
+	if !rv.IsValid() {
 		return OutputValueIsNilError
+	}
+	rvType := rv.Type()
+	if rvType.NumMethod() > 0 && rvType.Implements(luaValueType) {
+		return mapLuaValue(lv, rv)
+	}
+	if rvType == rawType {
+		return m.mapRaw(lv, rv)
+	}
+	if ud, ok := lv.(*lua.LUserData); ok {
+		return m.mapLuaUserDataToGoValue(ud, rv)
+	}
+
+	switch rv.Kind() {
+	case reflect.Int64, reflect.Ptr, reflect.Struct: // kinds of the special types
+		switch rvType {
+		case durationType:
+			return m.mapDuration(lv, rv, opts)
+		case timeType:
+			return mapTime(lv, rv, opts)
+		case locationPtrType:
+			return mapLocation(lv, rv)
+		case bigIntType:
+			return mapBigInt(lv, rv)
+		case bigFloatType:
+			return mapBigFloat(lv, rv)
+		case bigRatType:
+			return mapBigRat(lv, rv)
+		}
+	case reflect.Interface:
+		if m.variants != nil {
+			if info := m.variants[rvType]; info != nil {
+				return m.mapVariant(info, lv, rv)
+			}
+		}
+	}
+	if m.enums != nil {
+		if info := m.enums[rvType]; info != nil && lv.Type() != lua.LTNumber {
+			return m.mapEnum(info, lv, rv)
+		}
+	}
+	if s, ok := lv.(lua.LString); ok && opts != nil && opts["unit"] != "" && isNumberKind(rv.Kind()) {
+		return mapUnitString(string(s), rv, opts["unit"])
+	}
+
+	switch rv.Kind() {
 	case reflect.Bool:
 		return mapBool(lv, rv)
 	case reflect.Int:
@@ -84,104 +233,124 @@ func (m *Mapper) mapNonNilValue(lv lua.LValue, rv reflect.Value) error {
 	case reflect.Uint64:
 		return mapUint64(lv, rv)
 	case reflect.Uintptr:
-		return TBI
+		return mapUintptr(lv, rv)
 	case reflect.Float32:
 		return mapFloat32(lv, rv)
 	case reflect.Float64:
 		return mapFloat64(lv, rv)
-	case reflect.Complex64:
-		return TBI
-	case reflect.Complex128:
-		return TBI
+	case reflect.Complex64, reflect.Complex128:
+		return mapComplex(lv, rv)
 	case reflect.Array:
-		return m.mapArray(lv, rv)
+		return m.mapArray(lv, rv, opts)
 	case reflect.Chan:
-		return TBI
+		return m.mapChan(lv, rv)
 	case reflect.Func:
-		return TBI
+		return m.mapFunc(lv, rv)
 	case reflect.Interface:
-		return mapInterface(lv, rv)
+		return m.mapInterface(lv, rv)
 	case reflect.Map:
-		return m.mapMap(lv, rv)
+		return m.mapMap(lv, rv, opts)
 	case reflect.Ptr:
-		return m.mapPtr(lv, rv)
+		return m.mapPtr(lv, rv, opts)
 	case reflect.Slice:
-		return m.mapSlice(lv, rv)
+		return m.mapSlice(lv, rv, opts)
 	case reflect.String:
 		return mapString(lv, rv)
 	case reflect.Struct:
-		return m.mapStruct(lv, rv)
-	case reflect.UnsafePointer:
-		return TBI
+		return m.mapStruct(lv, rv, opts)
 	}
-	return fmt.Errorf("unsupported type: %s", rv.Kind())
+	// unsafe.Pointer can not be safely made from a Lua value
+	return &UnsupportedKindError{goType: rv.Type()}
 }
 
-func (m *Mapper) mapArray(lv lua.LValue, rv reflect.Value) error {
+func (m *Mapper) mapArray(lv lua.LValue, rv reflect.Value, opts tagOptions) error {
 	assert.True(lv != lua.LNil)
 	assert.True(rv.Kind() == reflect.Array)
 	switch v := lv.(type) {
 	case *lua.LTable:
-		return m.mapLuaTableToGoArray(v, rv)
-	case *lua.LUserData:
-		return mapLuaUserDataToGoValue(v, rv)
+		return m.mapLuaTableToGoArray(v, rv, opts)
+	case lua.LString:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			return mapLuaStringToGoByteArray(string(v), rv, opts)
+		}
 	}
 	return newTypeError(lv, rv)
 }
 
-func (m *Mapper) mapMap(lv lua.LValue, rv reflect.Value) error {
+func (m *Mapper) mapMap(lv lua.LValue, rv reflect.Value, opts tagOptions) error {
 	assert.True(lv != lua.LNil)
 	assert.True(rv.Kind() == reflect.Map)
-	switch v := lv.(type) {
-	case *lua.LTable:
-		return m.mapLuaTableToGoMap(v, rv)
-	case *lua.LUserData:
-		return mapLuaUserDataToGoValue(v, rv)
+	if tbl, ok := lv.(*lua.LTable); ok {
+		if fieldName := opts["keyby"]; fieldName != "" {
+			return m.mapLuaTableToGoMapByField(tbl, rv, fieldName, opts)
+		}
+		return m.mapLuaTableToGoMap(tbl, rv, opts)
 	}
 	return newTypeError(lv, rv)
 }
 
-func (m *Mapper) mapPtr(lv lua.LValue, rv reflect.Value) error {
+func (m *Mapper) mapPtr(lv lua.LValue, rv reflect.Value, opts tagOptions) error {
 	assert.True(lv != lua.LNil)
 	assert.True(rv.Kind() == reflect.Ptr)
-	if ud, ok := lv.(*lua.LUserData); ok {
-		return mapLuaUserDataToGoValue(ud, rv)
-	}
 	elemPtr := reflect.New(rv.Type().Elem())
-	if err := m.mapNonNilValue(lv, elemPtr.Elem()); err != nil {
+	if err := m.mapNonNilValue(lv, elemPtr.Elem(), opts); err != nil {
 		return err
 	}
 	rv.Set(elemPtr)
 	return nil
 }
 
-func (m *Mapper) mapSlice(lv lua.LValue, rv reflect.Value) error {
+func (m *Mapper) mapSlice(lv lua.LValue, rv reflect.Value, opts tagOptions) error {
 	assert.True(rv.Kind() == reflect.Slice)
 	switch v := lv.(type) {
 	case *lua.LTable:
-		return m.mapLuaTableToGoSlice(v, rv)
-	case *lua.LUserData:
-		return mapLuaUserDataToGoValue(v, rv)
+		return m.mapLuaTableToGoSlice(v, rv, opts)
+	case lua.LString:
+		switch rv.Type().Elem().Kind() {
+		case reflect.Uint8:
+			return mapLuaStringToGoBytes(string(v), rv, opts)
+		case reflect.Int32:
+			return mapLuaStringToGoRunes(string(v), rv, opts)
+		}
 	}
-	return newTypeError(lv, rv)
+	return m.mapScalarToGoSlice(lv, rv, opts)
 }
 
-func (m *Mapper) mapLuaTableToGoArray(tbl *lua.LTable, rv reflect.Value) error {
+func (m *Mapper) mapLuaTableToGoArray(tbl *lua.LTable, rv reflect.Value, opts tagOptions) error {
 	assert.True(tbl != nil)
 	assert.True(rv.Kind() == reflect.Array)
 	arrLen := rv.Len()
+	first, tblLen, err := m.getLuaArrayBounds(tbl, arrLen)
+	if err != nil {
+		return err
+	}
 	for i := 0; i < arrLen; i++ {
-		if err := m.MapValue(tbl.RawGetInt(i+1), rv.Index(i)); err != nil {
+		lv := lua.LValue(lua.LNil)
+		if i < tblLen {
+			lv = rawGetIndex(tbl, first+i)
+		}
+		if err := m.mapValue(lv, rv.Index(i), opts); err != nil {
 			return fmt.Errorf("array[%d]: %w", i, err)
 		}
 	}
 	return nil
 }
 
-func (m *Mapper) mapLuaTableToGoSlice(tbl *lua.LTable, rv reflect.Value) error {
+func (m *Mapper) mapLuaTableToGoSlice(tbl *lua.LTable, rv reflect.Value, opts tagOptions) error {
 	assert.True(tbl != nil)
 	assert.True(rv.Kind() == reflect.Slice)
-	tblLen := tbl.Len()
+	first, tblLen, err := m.getLuaArrayBounds(tbl, tbl.Len())
+	if err != nil {
+		return err
+	}
+	if tblLen == 0 {
+		if keyIndex := m.getKeyFieldIndex(rv.Type().Elem()); keyIndex >= 0 {
+			return m.mapKeyedLuaTableToGoSlice(tbl, rv, keyIndex, opts)
+		}
+		if keys, isSet := getLuaSetKeys(tbl); isSet && len(keys) > 0 && rv.Type().Elem().Kind() == reflect.String {
+			return m.mapLuaSetToGoSlice(keys, rv, opts)
+		}
+	}
 	rvCap := rv.Cap()
 	if rvCap < tblLen {
 		// reset to a new slice if need more capacity
@@ -192,69 +361,201 @@ func (m *Mapper) mapLuaTableToGoSlice(tbl *lua.LTable, rv reflect.Value) error {
 	}
 
 	for i := 0; i < tblLen; i++ {
-		if err := m.MapValue(tbl.RawGetInt(i+1), rv.Index(i)); err != nil {
+		if err := m.mapValue(rawGetIndex(tbl, first+i), rv.Index(i), opts); err != nil {
 			return fmt.Errorf("slice[%d]: %w", i, err)
 		}
 	}
 	return nil
 }
 
-func (m *Mapper) mapStruct(lv lua.LValue, rv reflect.Value) error {
+func (m *Mapper) mapStruct(lv lua.LValue, rv reflect.Value, opts tagOptions) error {
 	assert.True(lv != lua.LNil)
 	assert.True(rv.Kind() == reflect.Struct)
-	switch v := lv.(type) {
-	case *lua.LTable:
-		return m.mapLuaTableToGoStruct(v, rv)
-	case *lua.LUserData:
-		return mapLuaUserDataToGoValue(v, rv)
+	if tbl, ok := lv.(*lua.LTable); ok {
+		return m.mapLuaTableToGoStruct(tbl, rv, opts)
 	}
-	return newTypeError(lv, rv)
+	return m.mapShorthand(lv, rv)
 }
 
-func (m *Mapper) mapLuaTableToGoStruct(tbl *lua.LTable, rv reflect.Value) error {
+// mapLuaTableToGoStruct maps the Lua table to the struct.
+// The option "discriminator" in structOpts is the key consumed by the variant mapping.
+func (m *Mapper) mapLuaTableToGoStruct(tbl *lua.LTable, rv reflect.Value, structOpts tagOptions) error {
 	assert.True(tbl != nil)
 	assert.True(rv.Kind() == reflect.Struct)
 	rvType := rv.Type()
-	for i := 0; i < rv.NumField(); i++ {
-		fldVal := rv.Field(i)
+	info := m.getStructInfo(rvType)
+	if info.err != nil {
+		return info.err
+	}
+	var keys *consumedKeys // only needed by the remain field
+	if info.remainIndex >= 0 {
+		keys = newConsumedKeys()
+	}
+	if key := structOpts["discriminator"]; key != "" {
+		keys.add(&tableKey{text: key})
+	}
+	for i := range info.fields {
+		fi := &info.fields[i]
+		fldVal := rv.Field(fi.index)
 		if !fldVal.CanSet() {
-			continue // unexported field
+			continue // field of unexported embedded struct
 		}
 
-		field := rvType.Field(i)
-		fieldName := getFieldName(field, m.TagName)
-		if err := m.MapValue(tbl.RawGet(lua.LString(fieldName)), fldVal); err != nil {
-			return fmt.Errorf("%s: %w", field.Name, err)
+		var lv lua.LValue
+		var err error
+		if fi.pos > 0 {
+			keys.addPos(fi.pos)
+			lv, err = m.lookupPositionalField(tbl, fi, keys)
+		} else {
+			key := m.getFieldKey(fi)
+			keys.add(key)
+			for i := range fi.aliases {
+				keys.add(&fi.aliases[i])
+			}
+			lv, err = m.lookupField(tbl, rvType, fi, key)
+		}
+		if err == nil {
+			err = m.mapValue(lv, fldVal, fi.opts)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", fi.name, err)
 		}
 	}
+
+	if info.remainIndex >= 0 {
+		if err := m.mapRemain(tbl, keys, rv.Field(info.remainIndex)); err != nil {
+			return fmt.Errorf("%s: %w", rvType.Field(info.remainIndex).Name, err)
+		}
+		if rvType.Field(info.remainIndex).Type == lTablePtrType {
+			return nil // excess elements are kept in the remain table
+		}
+	}
+	if n := tbl.Len(); info.maxPos > 0 && n > info.maxPos {
+		return fmt.Errorf("excess elements: %d expected but got %d", info.maxPos, n)
+	}
 	return nil
 }
 
-// getFieldName get the struct field name.
-func getFieldName(field reflect.StructField, tagName string) string {
-	fieldName := field.Name
-	if tagName == "" {
-		return fieldName
+// lookupField gets the Lua value of the struct field by the key,
+// or by the aliases in the tag option "alias" like "alias=port|bind_port".
+// It is an error if more than one of them are set.
+// The use of a deprecated key is recorded as a warning
+// with the message of the tag option "deprecated".
+// The aliases are deprecated if any, otherwise the key is deprecated.
+func (m *Mapper) lookupField(tbl *lua.LTable, structType reflect.Type, fi *fieldInfo, key *tableKey) (lua.LValue, error) {
+	lv, err := m.getTableField(tbl, key)
+	if err != nil {
+		return lua.LNil, err
+	}
+	usedKey := key.text
+	isAlias := false
+	for i := range fi.aliases {
+		alias := &fi.aliases[i]
+		aliasVal, err := m.getTableField(tbl, alias)
+		if err != nil {
+			return lua.LNil, err
+		}
+		if aliasVal == lua.LNil {
+			continue
+		}
+		if lv != lua.LNil {
+			return lua.LNil, fmt.Errorf("conflicting keys %q and %q", usedKey, alias.text)
+		}
+		lv, usedKey, isAlias = aliasVal, alias.text, true
 	}
 
-	tagValue := field.Tag.Get(tagName)
-	tagSubValue := strings.SplitN(tagValue, ",", 2)[0]
-	if tagSubValue != "" {
-		return tagSubValue // use field name from tag value
+	if message, ok := fi.opts["deprecated"]; ok && lv != lua.LNil && (isAlias || len(fi.aliases) == 0) {
+		m.warn(Warning{
+			Field:   structType.String() + "." + fi.name,
+			Key:     usedKey,
+			Message: message,
+		})
 	}
-	return fieldName
+	return lv, nil
+}
+
+// tagOptions is the options following the name in a struct field tag,
+// such as "unit=ms" in `lua:"timeout,unit=ms"`.
+// An option without "=" is a flag with an empty value.
+type tagOptions map[string]string
+
+// lookupFieldTag gets the key name and the options in the struct field tags.
+// The key name is empty if no tag has a name, and is "-" if the field is skipped.
+// The options of the tags before the first tag with a name are added to its options
+// unless it has them, and the earlier of these tags take precedence.
+func (m *Mapper) lookupFieldTag(field reflect.StructField) (string, tagOptions) {
+	var opts tagOptions
+	for i := -1; i < len(m.TagNames); i++ { // -1 for TagName
+		tagName := m.TagName
+		if i >= 0 {
+			tagName = m.TagNames[i]
+		}
+		tagValue := field.Tag.Get(tagName)
+		if tagName == "" || tagValue == "" {
+			continue
+		}
+		if tagValue == "-" {
+			return "-", nil
+		}
+
+		tagSubValues := strings.Split(tagValue, ",")
+		if tagSubValues[0] != "" {
+			// use field name and options from the first tag with a name
+			return tagSubValues[0], mergeTagOptions(parseTagOptions(tagSubValues[1:]), opts)
+		}
+		opts = mergeTagOptions(opts, parseTagOptions(tagSubValues[1:]))
+	}
+	return "", opts
+}
+
+// mergeTagOptions adds the options of others which are not in opts.
+func mergeTagOptions(opts, others tagOptions) tagOptions {
+	if opts == nil {
+		return others
+	}
+	for k, v := range others {
+		if _, ok := opts[k]; !ok {
+			opts[k] = v
+		}
+	}
+	return opts
+}
+
+// parseTagOptions parses the tag options like "unit=ms".
+func parseTagOptions(tagSubValues []string) tagOptions {
+	if len(tagSubValues) == 0 {
+		return nil
+	}
+	opts := make(tagOptions, len(tagSubValues))
+	for _, sub := range tagSubValues {
+		kv := strings.SplitN(sub, "=", 2)
+		if len(kv) == 2 {
+			opts[kv[0]] = kv[1]
+		} else {
+			opts[kv[0]] = ""
+		}
+	}
+	return opts
 }
 
 // Always returns nil
-func (m *Mapper) mapLuaTableToGoMap(tbl *lua.LTable, rv reflect.Value) error {
+func (m *Mapper) mapLuaTableToGoMap(tbl *lua.LTable, rv reflect.Value, opts tagOptions) error {
 	assert.True(tbl != nil)
 	assert.True(rv.Kind() == reflect.Map)
 	mapType := rv.Type()
 	keyType := mapType.Key()
 	elemType := mapType.Elem()
+	if isEmptyStruct(elemType) || elemType.Kind() == reflect.Bool {
+		// a Lua set or a string array to a Go set, otherwise a normal map
+		if _, isSet := getLuaSetKeys(tbl); isSet && isEmptyStruct(elemType) || isStringArray(tbl) {
+			m.mapLuaSetToGoMap(tbl, rv)
+			return nil
+		}
+	}
 	if rv.IsNil() || rv.Len() > 0 { // reset map
 		rv.Set(reflect.MakeMap(mapType))
 	}
+	keyIndex := m.getKeyFieldIndex(elemType)
 	tbl.ForEach(func(lKey, lVal lua.LValue) {
 		rvKeyPtr := reflect.New(keyType) // rvKeyPtr is a pointer to a new zero key
 		rvKey := rvKeyPtr.Elem()
@@ -263,9 +564,14 @@ func (m *Mapper) mapLuaTableToGoMap(tbl *lua.LTable, rv reflect.Value) error {
 		}
 		rvElemPtr := reflect.New(elemType)
 		rvElem := rvElemPtr.Elem()
-		if err := m.MapValue(lVal, rvElemPtr.Elem()); err != nil {
+		if err := m.mapValue(lVal, rvElemPtr.Elem(), opts); err != nil {
 			return // skip field if error
 		}
+		if keyIndex >= 0 {
+			if err := m.injectKey(lKey, rvElem, keyIndex); err != nil {
+				return // skip field if error
+			}
+		}
 		rv.SetMapIndex(rvKey, rvElem)
 	})
 	return nil
@@ -124,3 +124,24 @@ func ExampleMapper_tagName() {
 	// default UserName: UserName
 	// with tag name: my_user_name
 }
+
+func ExampleMapper_nameFunc() {
+	L := lua.NewState()
+	if err := L.DoString(`person = {user_name = "Michel", work_place = "San Jose"}`); err != nil {
+		panic(err)
+	}
+
+	type Person struct {
+		UserName  string
+		WorkPlace string
+	}
+	var person Person
+	mapper := NewMapper()
+	mapper.NameFunc = SnakeCase // UserName -> user_name
+	if err := mapper.Map(L.GetGlobal("person"), &person); err != nil {
+		panic(err)
+	}
+	fmt.Printf("%s %s", person.UserName, person.WorkPlace)
+	// Output:
+	// Michel San Jose
+}
@@ -1,9 +1,11 @@
 package gluamapper
 
 import (
+	"errors"
 	"reflect"
 	"testing"
 	"time"
+	"unsafe"
 
 	"github.com/stretchr/testify/require"
 	"github.com/yuin/gopher-lua"
@@ -78,6 +80,51 @@ func TestTagName(t *testing.T) {
 	assert.Equal(B{Bbb: 123}, goB)
 }
 
+func TestTagNames(t *testing.T) {
+	var err error
+	assert := require.New(t)
+	L := lua.NewState()
+	err = L.DoString(`
+		a = {lua_name = 1, json_name = 2, json_only = 3, ms_name = 4, timeout = 5, Opts = "1KB"}
+	`)
+	assert.NoError(err)
+
+	type A struct {
+		Both    int           `lua:"lua_name" json:"json_name"`
+		JSON    int           `json:"json_only"`
+		MS      int           `lua:",omitempty" json:"" mapstructure:"ms_name"`
+		Timeout time.Duration `lua:",unit=s" json:"timeout,unit=ms"`
+		Opts    int           `lua:",unit=bytes" json:",unit=percent"`
+	}
+	m := NewMapperWithTagNames("lua", "json", "mapstructure")
+	var output A
+	err = m.Map(L.GetGlobal("a"), &output)
+	assert.NoError(err)
+	assert.Equal(A{Both: 1, JSON: 3, MS: 4, Timeout: 5 * time.Millisecond, Opts: 1000}, output)
+
+	type B struct {
+		Name   string                 `json:"name"`
+		Secret string                 `json:"-"`
+		Rest   map[string]interface{} `lua:",remain" json:"rest,omitempty"`
+	}
+	err = L.DoString(`b = {name = "n", ["-"] = "s", x = 1}`)
+	assert.NoError(err)
+	var b B
+	err = m.Map(L.GetGlobal("b"), &b)
+	assert.NoError(err)
+	assert.Equal(B{Name: "n", Rest: map[string]interface{}{"-": "s", "x": float64(1)}}, b)
+	lv, err := m.Encode(L, B{Name: "n", Secret: "s"})
+	assert.NoError(err)
+	assert.Equal(lua.LNil, lv.(*lua.LTable).RawGetString("-"))
+
+	m = NewMapperWithTagName("json")
+	m.TagNames = []string{"lua"}
+	err = m.Map(L.GetGlobal("a"), &output)
+	assert.EqualError(err, `Opts: illegal percentage "1KB"`) // options of json tag
+	assert.Equal(2, output.Both)
+	assert.Equal(0, output.MS)
+}
+
 func TestMapMap(t *testing.T) {
 	var err error
 	var output map[int]int
@@ -247,9 +294,14 @@ func TestMapStruct(t *testing.T) {
 	assert.NoError(err)
 	assert.Equal("name", output.Name)
 
-	ud.Value = &testPerson{}
+	ud.Value = &testPerson{Name: "pointer"}
 	err = Map(ud, &output)
-	assert.EqualError(err, "gluamapper.testPerson expected but got Lua user data of *gluamapper.testPerson")
+	assert.NoError(err)
+	assert.Equal("pointer", output.Name)
+
+	ud.Value = &testRole{}
+	err = Map(ud, &output)
+	assert.EqualError(err, "gluamapper.testPerson expected but got Lua user data of *gluamapper.testRole")
 }
 
 func TestMapStructWithUnexportedField(t *testing.T) {
@@ -283,10 +335,13 @@ func TestMapStructWithUnexportedField(t *testing.T) {
 		t = {wall = 1234}
 	`)
 	assert.NoError(err)
-	var tm time.Time // struct { wall uint64, ...}
+	type wallTime struct {
+		wall uint64
+	}
+	var tm wallTime
 	err = Map(L.GetGlobal("t"), &tm)
 	assert.NoError(err)
-	assert.Equal(time.Time{}, tm) // wall is unexported
+	assert.Equal(wallTime{}, tm) // wall is unexported
 }
 
 func TestValueOfNil(t *testing.T) {
@@ -343,3 +398,12 @@ func TestMapArray(t *testing.T) {
 	err = Map(ud, &c)
 	assert.EqualError(err, "[2]bool expected but got Lua user data of [3]int")
 }
+
+func TestMapUnsupportedKind(t *testing.T) {
+	assert := require.New(t)
+	var p unsafe.Pointer
+	err := Map(lua.LNumber(1), &p)
+	assert.EqualError(err, "unsupported kind unsafe.Pointer of unsafe.Pointer")
+	var uke *UnsupportedKindError
+	assert.True(errors.As(err, &uke))
+}
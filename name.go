@@ -0,0 +1,96 @@
+package gluamapper
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/yuin/gopher-lua"
+)
+
+// NameFunc converts a Go struct field name into a Lua table key.
+// It is used for the fields without a key name in the tag.
+type NameFunc func(fieldName string) string
+
+// SnakeCase converts "WorkPlace" into "work_place", and "HTTPServer" into "http_server".
+func SnakeCase(fieldName string) string {
+	return strings.Join(lowerWords(fieldName), "_")
+}
+
+// KebabCase converts "WorkPlace" into "work-place", and "HTTPServer" into "http-server".
+func KebabCase(fieldName string) string {
+	return strings.Join(lowerWords(fieldName), "-")
+}
+
+// CamelCase converts "WorkPlace" into "workPlace", and "HTTPServer" into "httpServer".
+func CamelCase(fieldName string) string {
+	words := lowerWords(fieldName)
+	for i := 1; i < len(words); i++ {
+		runes := []rune(words[i])
+		runes[0] = unicode.ToUpper(runes[0])
+		words[i] = string(runes)
+	}
+	return strings.Join(words, "")
+}
+
+// LowerCase converts "WorkPlace" into "workplace".
+func LowerCase(fieldName string) string {
+	return strings.ToLower(fieldName)
+}
+
+// lowerWords splits the field name into lower case words,
+// at the case changes, digits to upper case, and underscores.
+func lowerWords(fieldName string) []string {
+	var words []string
+	runes := []rune(fieldName)
+	start := 0
+	for i := 0; i <= len(runes); i++ {
+		if i < len(runes) && runes[i] != '_' && !isWordStart(runes, i) {
+			continue
+		}
+		if i > start {
+			words = append(words, strings.ToLower(string(runes[start:i])))
+		}
+		start = i
+		if i < len(runes) && runes[i] == '_' {
+			start = i + 1
+		}
+	}
+	return words
+}
+
+// isWordStart reports whether runes[i] starts a new word,
+// like "P" in "WorkPlace" and "S" in "HTTPServer".
+func isWordStart(runes []rune, i int) bool {
+	if i == 0 || !unicode.IsUpper(runes[i]) {
+		return false
+	}
+	prev := runes[i-1]
+	if unicode.IsLower(prev) || unicode.IsDigit(prev) {
+		return true
+	}
+	return unicode.IsUpper(prev) && i+1 < len(runes) && unicode.IsLower(runes[i+1])
+}
+
+// getTableKey gets the value of the key from the Lua table.
+// If the key is absent and m.CaseInsensitive is true, it looks for a key
+// which is equal to the key under Unicode case-folding,
+// and returns an error if more than one key matches.
+func (m *Mapper) getTableKey(tbl *lua.LTable, key string) (lua.LValue, error) {
+	lv := tbl.RawGetString(key)
+	if lv != lua.LNil || !m.CaseInsensitive {
+		return lv, nil
+	}
+
+	var matchedKeys []string
+	tbl.ForEach(func(lKey, lVal lua.LValue) {
+		if s, ok := lKey.(lua.LString); ok && strings.EqualFold(string(s), key) {
+			matchedKeys = append(matchedKeys, string(s))
+			lv = lVal
+		}
+	})
+	if len(matchedKeys) > 1 {
+		return lua.LNil, fmt.Errorf("ambiguous keys %q for %q", matchedKeys, key)
+	}
+	return lv, nil
+}
@@ -0,0 +1,83 @@
+package gluamapper
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yuin/gopher-lua"
+)
+
+func TestNameFuncs(t *testing.T) {
+	assert := require.New(t)
+	tests := []struct {
+		name, snake, kebab, camel, lower string
+	}{
+		{"WorkPlace", "work_place", "work-place", "workPlace", "workplace"},
+		{"HTTPServer", "http_server", "http-server", "httpServer", "httpserver"},
+		{"UserID", "user_id", "user-id", "userId", "userid"},
+		{"Age", "age", "age", "age", "age"},
+		{"V2Api", "v2_api", "v2-api", "v2Api", "v2api"},
+		{"Max_Size", "max_size", "max-size", "maxSize", "max_size"},
+		{"A", "a", "a", "a", "a"},
+	}
+	for _, test := range tests {
+		assert.Equal(test.snake, SnakeCase(test.name), test.name)
+		assert.Equal(test.kebab, KebabCase(test.name), test.name)
+		assert.Equal(test.camel, CamelCase(test.name), test.name)
+		assert.Equal(test.lower, LowerCase(test.name), test.name)
+	}
+}
+
+func TestMapWithNameFunc(t *testing.T) {
+	var err error
+	assert := require.New(t)
+	L := lua.NewState()
+	err = L.DoString(`
+		person = {
+			name = "Michel",
+			work_place = "San Jose",
+			role = {{name = "Administrator"}},
+		}
+	`)
+	assert.NoError(err)
+
+	type Person struct {
+		Name      string
+		Age       int `lua:"years"`
+		WorkPlace string
+		Role      []*testRole
+	}
+	m := NewMapperWithTagName("lua")
+	m.NameFunc = SnakeCase
+	var person Person
+	err = m.Map(L.GetGlobal("person"), &person)
+	assert.NoError(err)
+	assert.Equal(Person{Name: "Michel", WorkPlace: "San Jose", Role: []*testRole{{Name: "Administrator"}}}, person)
+
+	lv, err := m.Encode(L, Person{WorkPlace: "here", Age: 3})
+	assert.NoError(err)
+	assert.Equal(lua.LString("here"), lv.(*lua.LTable).RawGetString("work_place"))
+	assert.Equal(lua.LNumber(3), lv.(*lua.LTable).RawGetString("years"))
+}
+
+func TestMapCaseInsensitive(t *testing.T) {
+	var err error
+	assert := require.New(t)
+	L := lua.NewState()
+	err = L.DoString(`
+		person = {NAME = "Michel", workplace = "San Jose", Age = 31, AGE = 32}
+		ambiguous = {NAME = "a", name = "b"}
+	`)
+	assert.NoError(err)
+
+	m := NewMapper()
+	m.CaseInsensitive = true
+	var person testPerson
+	err = m.Map(L.GetGlobal("person"), &person)
+	assert.NoError(err)
+	assert.Equal(testPerson{Name: "Michel", Age: 31, WorkPlace: "San Jose"}, person)
+
+	err = m.Map(L.GetGlobal("ambiguous"), &person)
+	assert.Error(err)
+	assert.Regexp(`^Name: ambiguous keys \["(NAME|name)" "(NAME|name)"\] for "Name"$`, err.Error())
+}
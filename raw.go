@@ -0,0 +1,51 @@
+package gluamapper
+
+import (
+	"reflect"
+
+	assert "github.com/arl/assertgo"
+	"github.com/yuin/gopher-lua"
+)
+
+var rawType = reflect.TypeOf(Raw{})
+
+// Raw is a Lua value whose mapping is deferred, like json.RawMessage.
+// Mapping into a Raw captures the Lua value and the settings of the Mapper,
+// and Decode maps the value later, once the Go type is known.
+// Encoding a Raw encodes the Lua value untouched.
+type Raw struct {
+	// Value is the captured Lua value, nil if the Raw is not mapped.
+	Value lua.LValue
+
+	mapper *Mapper
+}
+
+// Decode maps the captured Lua value to the Go value pointed by output,
+// with the settings of the Mapper which captured it.
+// See Mapper.Map.
+func (r *Raw) Decode(output interface{}) error {
+	m := r.mapper
+	if m == nil {
+		m = defaultMapper
+	}
+	lv := r.Value
+	if lv == nil {
+		lv = lua.LNil
+	}
+	return m.Map(lv, output)
+}
+
+func (m *Mapper) mapRaw(lv lua.LValue, rv reflect.Value) error {
+	assert.True(rv.Type() == rawType)
+	mapper := *m // settings at the time of capture
+	rv.Set(reflect.ValueOf(Raw{Value: lv, mapper: &mapper}))
+	return nil
+}
+
+func encodeRaw(rv reflect.Value) lua.LValue {
+	assert.True(rv.Type() == rawType)
+	if lv := rv.Interface().(Raw).Value; lv != nil {
+		return lv
+	}
+	return lua.LNil
+}
@@ -0,0 +1,62 @@
+package gluamapper
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yuin/gopher-lua"
+)
+
+func TestRaw(t *testing.T) {
+	var err error
+	assert := require.New(t)
+	L := lua.NewState()
+	err = L.DoString(`
+		plugins = {
+			{kind = "cache", options = {size_mb = 64}},
+			{kind = "auth", options = {users = {"alice"}}},
+		}
+	`)
+	assert.NoError(err)
+
+	type Plugin struct {
+		Kind    string
+		Options Raw
+	}
+	type CacheOptions struct {
+		SizeMB int
+	}
+	type AuthOptions struct {
+		Users []string
+	}
+	m := NewMapperWithTagName("lua")
+	m.NameFunc = SnakeCase
+	var plugins []Plugin
+	err = m.Map(L.GetGlobal("plugins"), &plugins)
+	assert.NoError(err)
+	assert.Len(plugins, 2)
+	m.NameFunc = nil // Decode uses the captured settings
+
+	var cache CacheOptions
+	assert.Equal("cache", plugins[0].Kind)
+	assert.NoError(plugins[0].Options.Decode(&cache))
+	assert.Equal(CacheOptions{SizeMB: 64}, cache)
+
+	var auth AuthOptions
+	assert.Equal("auth", plugins[1].Kind)
+	assert.NoError(plugins[1].Options.Decode(&auth))
+	assert.Equal(AuthOptions{Users: []string{"alice"}}, auth)
+	var n int
+	assert.EqualError(plugins[1].Options.Decode(&n), "int expected but got Lua table")
+
+	lv, err := m.Encode(L, plugins[0])
+	assert.NoError(err)
+	assert.Same(plugins[0].Options.Value, lv.(*lua.LTable).RawGetString("Options"))
+
+	var raw Raw
+	assert.NoError(m.Map(lua.LNil, &raw))
+	assert.Equal(lua.LNil, raw.Value)
+	assert.NoError(raw.Decode(&cache))
+	assert.Equal(CacheOptions{}, cache)
+	assert.NoError((&Raw{}).Decode(&cache))
+}
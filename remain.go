@@ -0,0 +1,193 @@
+package gluamapper
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/yuin/gopher-lua"
+)
+
+var (
+	interfaceMapType = reflect.TypeOf(map[string]interface{}(nil))
+	lvalueMapType    = reflect.TypeOf(map[string]lua.LValue(nil))
+	lTablePtrType    = reflect.TypeOf((*lua.LTable)(nil))
+)
+
+// consumedKeys is the tree of the Lua table keys which are mapped to the struct fields.
+// A key path like "server.host" consumes only the key "host" of the nested table "server",
+// so the other keys of "server" are left to the remain field.
+// A nil *consumedKeys consumes nothing, and is used if the struct has no remain field.
+type consumedKeys struct {
+	names     map[string]*consumedKeys // nil value if the whole entry is consumed
+	positions map[int]bool
+}
+
+func newConsumedKeys() *consumedKeys {
+	return &consumedKeys{names: map[string]*consumedKeys{}, positions: map[int]bool{}}
+}
+
+func (keys *consumedKeys) add(key *tableKey) {
+	if keys == nil {
+		return
+	}
+	if key.path == nil {
+		keys.names[key.text] = nil
+		return
+	}
+	last := len(key.path) - 1
+	for _, name := range key.path[:last] {
+		sub, ok := keys.names[name]
+		if ok && sub == nil {
+			return // the whole nested table is consumed
+		}
+		if !ok {
+			sub = newConsumedKeys()
+			keys.names[name] = sub
+		}
+		keys = sub
+	}
+	keys.names[key.path[last]] = nil
+}
+
+func (keys *consumedKeys) addPos(pos int) {
+	if keys != nil {
+		keys.positions[pos] = true
+	}
+}
+
+// findConsumedKey finds the Lua table key in the consumed keys,
+// ignoring the case if m.CaseInsensitive is true.
+// The returned sub keys are nil if the whole entry is consumed,
+// or the consumed keys of the nested table.
+func (m *Mapper) findConsumedKey(keys *consumedKeys, lKey lua.LValue) (sub *consumedKeys, found bool) {
+	if n, ok := lKey.(lua.LNumber); ok {
+		return nil, float64(n) == float64(int(n)) && keys.positions[int(n)]
+	}
+	key, ok := lKey.(lua.LString)
+	if !ok {
+		return nil, false
+	}
+	if sub, ok := keys.names[string(key)]; ok {
+		return sub, true
+	}
+	if !m.CaseInsensitive {
+		return nil, false
+	}
+	for consumed, sub := range keys.names {
+		if strings.EqualFold(consumed, string(key)) {
+			return sub, true
+		}
+	}
+	return nil, false
+}
+
+// filterConsumed copies the entries of the Lua table which are not consumed,
+// and the nested tables with their keys partly consumed by the key paths.
+// Returns the new table and the number of its entries.
+func (m *Mapper) filterConsumed(tbl *lua.LTable, keys *consumedKeys) (*lua.LTable, int) {
+	remain := &lua.LTable{Metatable: lua.LNil}
+	n := 0
+	tbl.ForEach(func(lKey, lVal lua.LValue) {
+		sub, found := m.findConsumedKey(keys, lKey)
+		if found && sub == nil {
+			return
+		}
+		if nested, ok := lVal.(*lua.LTable); ok && found {
+			rest, restN := m.filterConsumed(nested, sub)
+			if restN == 0 {
+				return
+			}
+			lVal = rest
+		}
+		remain.RawSet(lKey, lVal)
+		n++
+	})
+	return remain, n
+}
+
+// mapRemain maps the entries of the Lua table which are not consumed
+// into the struct field with the tag option "remain".
+// The field must be map[string]interface{}, map[string]lua.LValue or *lua.LTable.
+func (m *Mapper) mapRemain(tbl *lua.LTable, keys *consumedKeys, rv reflect.Value) error {
+	rvType := rv.Type()
+	if rvType != interfaceMapType && rvType != lvalueMapType && rvType != lTablePtrType {
+		return fmt.Errorf("remain field must be map[string]interface{}, map[string]lua.LValue or *lua.LTable, but got %s", rvType)
+	}
+
+	remain, _ := m.filterConsumed(tbl, keys)
+	if rvType == lTablePtrType {
+		rv.Set(reflect.ValueOf(remain))
+		return nil
+	}
+
+	mp := reflect.MakeMap(rvType)
+	var err error
+	remain.ForEach(func(lKey, lVal lua.LValue) {
+		key, ok := toString(lKey)
+		if !ok || err != nil {
+			return
+		}
+		if rvType == lvalueMapType {
+			mp.SetMapIndex(reflect.ValueOf(key), reflect.ValueOf(&lVal).Elem())
+			return
+		}
+		itf, itfErr := m.toInterface(lVal)
+		if itfErr != nil {
+			err = fmt.Errorf("%s: %w", key, itfErr)
+			return
+		}
+		mp.SetMapIndex(reflect.ValueOf(key), reflect.ValueOf(&itf).Elem())
+	})
+	if err != nil {
+		return err
+	}
+	rv.Set(mp)
+	return nil
+}
+
+// encodeRemain writes the entries of the remain field into the Lua table,
+// except the keys which are already set by the other fields.
+// A nested table is merged into the table already set by the key paths.
+func (m *Mapper) encodeRemain(L *lua.LState, tbl *lua.LTable, rv reflect.Value) error {
+	if rv.IsNil() {
+		return nil
+	}
+	if remain, ok := rv.Interface().(*lua.LTable); ok {
+		remain.ForEach(func(lKey, lVal lua.LValue) {
+			mergeTableEntry(tbl, lKey, lVal)
+		})
+		return nil
+	}
+	if rv.Kind() != reflect.Map || rv.Type().Key().Kind() != reflect.String {
+		return fmt.Errorf("remain field must be map[string]interface{}, map[string]lua.LValue or *lua.LTable, but got %s", rv.Type())
+	}
+
+	iter := rv.MapRange()
+	for iter.Next() {
+		key := iter.Key().String()
+		lv, err := m.encodeValue(L, iter.Value(), nil)
+		if err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		mergeTableEntry(tbl, lua.LString(key), lv)
+	}
+	return nil
+}
+
+// mergeTableEntry sets the entry into the Lua table if the key is absent,
+// or merges it recursively if both the old and the new values are tables.
+func mergeTableEntry(tbl *lua.LTable, lKey, lVal lua.LValue) {
+	old := tbl.RawGet(lKey)
+	if old == lua.LNil {
+		tbl.RawSet(lKey, lVal)
+		return
+	}
+	oldTbl, ok := old.(*lua.LTable)
+	newTbl, ok2 := lVal.(*lua.LTable)
+	if ok && ok2 && oldTbl != newTbl {
+		newTbl.ForEach(func(k, v lua.LValue) {
+			mergeTableEntry(oldTbl, k, v)
+		})
+	}
+}
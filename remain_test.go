@@ -0,0 +1,151 @@
+package gluamapper
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yuin/gopher-lua"
+)
+
+func TestMapRemain(t *testing.T) {
+	var err error
+	assert := require.New(t)
+	L := lua.NewState()
+	err = L.DoString(`
+		plugin = {
+			name = "cache",
+			old_port = 80,
+			server = {host = "localhost"},
+			x_vendor = "acme",
+			x_limits = {1, 2},
+		}
+	`)
+	assert.NoError(err)
+
+	type Plugin struct {
+		Name  string                 `lua:"name"`
+		Port  int                    `lua:"port,alias=old_port"`
+		Host  string                 `lua:"server.host"`
+		Extra map[string]interface{} `lua:",remain"`
+	}
+	m := NewMapperWithTagName("lua")
+	var plugin Plugin
+	err = m.Map(L.GetGlobal("plugin"), &plugin)
+	assert.NoError(err)
+	assert.Equal(Plugin{
+		Name: "cache",
+		Port: 80,
+		Host: "localhost",
+		Extra: map[string]interface{}{
+			"x_vendor": "acme",
+			"x_limits": []interface{}{float64(1), float64(2)},
+		},
+	}, plugin)
+
+	lv, err := m.Encode(L, plugin)
+	assert.NoError(err)
+	tbl := lv.(*lua.LTable)
+	assert.Equal(lua.LString("cache"), tbl.RawGetString("name"))
+	assert.Equal(lua.LString("acme"), tbl.RawGetString("x_vendor"))
+	assert.Equal(lua.LNumber(2), tbl.RawGetString("x_limits").(*lua.LTable).RawGetInt(2))
+
+	type LValuePlugin struct {
+		Name  string
+		Extra map[string]lua.LValue `lua:",remain"`
+	}
+	var lvaluePlugin LValuePlugin
+	m.CaseInsensitive = true
+	err = m.Map(L.GetGlobal("plugin"), &lvaluePlugin)
+	assert.NoError(err)
+	assert.Equal("cache", lvaluePlugin.Name)
+	assert.Len(lvaluePlugin.Extra, 4)
+	assert.Equal(lua.LString("acme"), lvaluePlugin.Extra["x_vendor"])
+
+	type TablePlugin struct {
+		Name  string      `lua:"name"`
+		Extra *lua.LTable `lua:",remain"`
+	}
+	var tablePlugin TablePlugin
+	err = m.Map(L.GetGlobal("plugin"), &tablePlugin)
+	assert.NoError(err)
+	assert.Equal(lua.LNil, tablePlugin.Extra.RawGetString("name"))
+	assert.Equal(lua.LNumber(80), tablePlugin.Extra.RawGetString("old_port"))
+
+	lv, err = m.Encode(L, TablePlugin{Name: "new", Extra: tablePlugin.Extra})
+	assert.NoError(err)
+	assert.Equal(lua.LString("new"), lv.(*lua.LTable).RawGetString("name"))
+	assert.Equal(lua.LNumber(80), lv.(*lua.LTable).RawGetString("old_port"))
+
+	type BadPlugin struct {
+		Extra map[string]string `lua:",remain"`
+	}
+	err = m.Map(L.GetGlobal("plugin"), &BadPlugin{})
+	assert.EqualError(err, "Extra: remain field must be map[string]interface{}, map[string]lua.LValue or *lua.LTable, but got map[string]string")
+}
+
+func TestMapRemainOfVariant(t *testing.T) {
+	var err error
+	assert := require.New(t)
+	L := lua.NewState()
+	err = L.DoString(`storage = {type = "local", path = "/tmp", mode = "0644"}`)
+	assert.NoError(err)
+
+	type localStorage struct {
+		Path  string
+		Extra map[string]interface{} `lua:",remain"`
+	}
+	type storage interface{}
+	m := NewMapperWithTagName("lua")
+	m.CaseInsensitive = true
+	storageType := reflect.TypeOf((*storage)(nil)).Elem()
+	m.RegisterType(storageType, "local", reflect.TypeOf(localStorage{}))
+	var s storage
+	err = m.Map(L.GetGlobal("storage"), &s)
+	assert.NoError(err)
+	assert.Equal(localStorage{Path: "/tmp", Extra: map[string]interface{}{"mode": "0644"}}, s)
+}
+
+func TestMapRemainOfKeyPath(t *testing.T) {
+	var err error
+	assert := require.New(t)
+	L := lua.NewState()
+	err = L.DoString(`
+		config = {
+			server = {host = "localhost", port = 8080, tls = {cert = "a.pem", key = "a.key"}},
+			db = {url = "mysql://"},
+		}
+	`)
+	assert.NoError(err)
+
+	type Config struct {
+		Host  string                 `lua:"server.host"`
+		Cert  string                 `lua:"server.tls.cert"`
+		URL   string                 `lua:"db.url"`
+		Extra map[string]interface{} `lua:",remain"`
+	}
+	m := NewMapperWithTagName("lua")
+	var config Config
+	err = m.Map(L.GetGlobal("config"), &config)
+	assert.NoError(err)
+	assert.Equal(Config{
+		Host: "localhost",
+		Cert: "a.pem",
+		URL:  "mysql://",
+		Extra: map[string]interface{}{
+			"server": map[string]interface{}{
+				"port": float64(8080),
+				"tls":  map[string]interface{}{"key": "a.key"},
+			},
+		},
+	}, config)
+
+	lv, err := m.Encode(L, config)
+	assert.NoError(err)
+	server := lv.(*lua.LTable).RawGetString("server").(*lua.LTable)
+	assert.Equal(lua.LString("localhost"), server.RawGetString("host"))
+	assert.Equal(lua.LNumber(8080), server.RawGetString("port"))
+	tls := server.RawGetString("tls").(*lua.LTable)
+	assert.Equal(lua.LString("a.pem"), tls.RawGetString("cert"))
+	assert.Equal(lua.LString("a.key"), tls.RawGetString("key"))
+}
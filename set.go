@@ -0,0 +1,116 @@
+package gluamapper
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+
+	assert "github.com/arl/assertgo"
+	"github.com/yuin/gopher-lua"
+)
+
+// getLuaSetKeys gets the keys with the true values of the Lua set table
+// like {read = true, write = false}.
+// Returns false if the table is not a set, which has a sequence or a non-boolean value.
+func getLuaSetKeys(tbl *lua.LTable) ([]lua.LValue, bool) {
+	if tbl.Len() > 0 {
+		return nil, false
+	}
+	var keys []lua.LValue
+	isSet := true
+	tbl.ForEach(func(lKey, lVal lua.LValue) {
+		switch lVal {
+		case lua.LTrue:
+			keys = append(keys, lKey)
+		case lua.LFalse:
+		default:
+			isSet = false
+		}
+	})
+	return keys, isSet
+}
+
+// isStringArray reports whether the Lua table is a non-empty array of strings like {"read", "write"}.
+func isStringArray(tbl *lua.LTable) bool {
+	n := tbl.Len()
+	for i := 1; i <= n; i++ {
+		if _, ok := tbl.RawGetInt(i).(lua.LString); !ok {
+			return false
+		}
+	}
+	return n > 0
+}
+
+// isEmptyStruct reports whether the type is struct{}, which is the element of a Go set.
+func isEmptyStruct(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && t.NumField() == 0
+}
+
+// mapLuaSetToGoSlice maps the Lua set table to the slice of the keys sorted.
+func (m *Mapper) mapLuaSetToGoSlice(keys []lua.LValue, rv reflect.Value, opts tagOptions) error {
+	assert.True(rv.Kind() == reflect.Slice)
+	names := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if name, ok := key.(lua.LString); ok {
+			names = append(names, string(name))
+		}
+	}
+	sort.Strings(names)
+
+	slc := reflect.MakeSlice(rv.Type(), len(names), len(names))
+	for i, name := range names {
+		if err := m.mapValue(lua.LString(name), slc.Index(i), opts); err != nil {
+			return fmt.Errorf("slice[%d]: %w", i, err)
+		}
+	}
+	rv.Set(slc)
+	return nil
+}
+
+// mapLuaSetToGoMap maps the Lua set table like {read = true} to map[K]struct{},
+// or the Lua array like {"read", "write"} to map[K]struct{} or map[K]bool.
+// The keys which fail to map are skipped like mapLuaTableToGoMap.
+func (m *Mapper) mapLuaSetToGoMap(tbl *lua.LTable, rv reflect.Value) {
+	assert.True(rv.Kind() == reflect.Map)
+	mapType := rv.Type()
+	keys, isSet := getLuaSetKeys(tbl)
+	if !isSet {
+		for i := 1; i <= tbl.Len(); i++ {
+			keys = append(keys, tbl.RawGetInt(i))
+		}
+	}
+
+	elem := reflect.New(mapType.Elem()).Elem()
+	if elem.Kind() == reflect.Bool {
+		elem.SetBool(true)
+	}
+	mp := reflect.MakeMapWithSize(mapType, len(keys))
+	for _, key := range keys {
+		rvKey := reflect.New(mapType.Key()).Elem()
+		if err := m.MapValue(key, rvKey); err != nil {
+			continue // skip key if error
+		}
+		mp.SetMapIndex(rvKey, elem)
+	}
+	rv.Set(mp)
+}
+
+// mapLuaSetToGoFlags maps the Lua set table like {read = true, write = true}
+// to the registered bit flag type.
+func (m *Mapper) mapLuaSetToGoFlags(info *enumInfo, keys []lua.LValue, rv reflect.Value) error {
+	assert.True(info.isFlags)
+	var bits uint64
+	for _, key := range keys {
+		name, ok := key.(lua.LString)
+		if !ok {
+			return fmt.Errorf("key %s: %w", key, newTypeError(key, rv))
+		}
+		value, err := info.lookup(string(name), rv.Type())
+		if err != nil {
+			return err
+		}
+		bits |= toUint64(value)
+	}
+	setUint64(rv, bits)
+	return nil
+}
@@ -0,0 +1,61 @@
+package gluamapper
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yuin/gopher-lua"
+)
+
+func TestMapSet(t *testing.T) {
+	var err error
+	assert := require.New(t)
+	L := lua.NewState()
+	err = L.DoString(`
+		set = {write = true, read = true, exec = false}
+		array = {"read", "write"}
+		bad = {read = true, size = 1}
+		unknown = {delete = true}
+		objects = {a = {}}
+	`)
+	assert.NoError(err)
+
+	m := newTestEnumMapper()
+	var set map[string]struct{}
+	err = m.Map(L.GetGlobal("set"), &set)
+	assert.NoError(err)
+	assert.Equal(map[string]struct{}{"read": {}, "write": {}}, set)
+	err = m.Map(L.GetGlobal("array"), &set)
+	assert.NoError(err)
+	assert.Equal(map[string]struct{}{"read": {}, "write": {}}, set)
+	err = m.Map(L.GetGlobal("objects"), &set)
+	assert.NoError(err)
+	assert.Equal(map[string]struct{}{"a": {}}, set)
+
+	var names []string
+	err = m.Map(L.GetGlobal("set"), &names)
+	assert.NoError(err)
+	assert.Equal([]string{"read", "write"}, names)
+	err = m.Map(L.GetGlobal("bad"), &names)
+	assert.NoError(err)
+	assert.Empty(names)
+
+	var perm testPerm
+	err = m.Map(L.GetGlobal("set"), &perm)
+	assert.NoError(err)
+	assert.Equal(testPermRead|testPermWrite, perm)
+	err = m.Map(L.GetGlobal("unknown"), &perm)
+	assert.EqualError(err, `unknown gluamapper.testPerm "delete", valid choices: exec, read, rw, write`)
+
+	var bools map[string]bool
+	err = m.Map(L.GetGlobal("array"), &bools)
+	assert.NoError(err)
+	assert.Equal(map[string]bool{"read": true, "write": true}, bools)
+	err = m.Map(L.GetGlobal("set"), &bools)
+	assert.NoError(err)
+	assert.Equal(map[string]bool{"read": true, "write": true, "exec": false}, bools)
+
+	lv, err := m.Encode(L, map[string]struct{}{"read": {}})
+	assert.NoError(err)
+	assert.Equal(lua.LTrue, lv.(*lua.LTable).RawGetString("read"))
+}
@@ -0,0 +1,53 @@
+package gluamapper
+
+import (
+	"fmt"
+	"reflect"
+
+	assert "github.com/arl/assertgo"
+	"github.com/yuin/gopher-lua"
+)
+
+// LuaShorthand is implemented by the struct types which can be mapped
+// from a non-table Lua value, like `listen = 8080` for `listen = {port = 8080}`.
+// LuaShorthandField returns the name of the struct field which the value fills.
+// The tag option "shorthand", like `lua:"port,shorthand"`, is the alternative.
+type LuaShorthand interface {
+	LuaShorthandField() string
+}
+
+var luaShorthandType = reflect.TypeOf((*LuaShorthand)(nil)).Elem()
+
+// mapShorthand maps the non-table Lua value to the shorthand field of the struct,
+// and resets the other fields.
+func (m *Mapper) mapShorthand(lv lua.LValue, rv reflect.Value) error {
+	assert.True(rv.Kind() == reflect.Struct)
+	info := m.getStructInfo(rv.Type())
+	fi := info.shorthand
+	if fi == nil {
+		return newTypeError(lv, rv)
+	}
+	value := reflect.New(rv.Type()).Elem()
+	if err := m.mapValue(lv, value.Field(fi.index), fi.opts); err != nil {
+		return fmt.Errorf("%s: %w", fi.name, err)
+	}
+	rv.Set(value)
+	return nil
+}
+
+// mapScalarToGoSlice promotes the non-table Lua value to a single element slice,
+// like "10.0.0.1" for {"10.0.0.1"}.
+// Returns the TypeError of the slice if the element type does not accept the Lua type,
+// or the error of the element if its value is invalid, like "1x" for []time.Duration.
+func (m *Mapper) mapScalarToGoSlice(lv lua.LValue, rv reflect.Value, opts tagOptions) error {
+	assert.True(rv.Kind() == reflect.Slice)
+	slc := reflect.MakeSlice(rv.Type(), 1, 1)
+	if err := m.mapNonNilValue(lv, slc.Index(0), opts); err != nil {
+		if _, ok := err.(*TypeError); ok {
+			return newTypeError(lv, rv)
+		}
+		return fmt.Errorf("slice[0]: %w", err)
+	}
+	rv.Set(slc)
+	return nil
+}
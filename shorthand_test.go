@@ -0,0 +1,88 @@
+package gluamapper
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yuin/gopher-lua"
+)
+
+type testListen struct {
+	Host string `lua:"host"`
+	Port int    `lua:"port,shorthand"`
+}
+
+type testUpstream struct {
+	Addrs []string `lua:"addrs"`
+}
+
+func (*testUpstream) LuaShorthandField() string {
+	return "Addrs"
+}
+
+func TestMapShorthand(t *testing.T) {
+	var err error
+	assert := require.New(t)
+	L := lua.NewState()
+	err = L.DoString(`
+		short = {listen = 8080, upstream = "10.0.0.1"}
+		long = {
+			listen = {host = "0.0.0.0", port = 8080},
+			upstream = {addrs = {"10.0.0.1", "10.0.0.2"}},
+		}
+		bad = {listen = true}
+	`)
+	assert.NoError(err)
+
+	type Config struct {
+		Listen   testListen    `lua:"listen"`
+		Upstream *testUpstream `lua:"upstream"`
+	}
+	m := NewMapperWithTagName("lua")
+	var config Config
+	err = m.Map(L.GetGlobal("short"), &config)
+	assert.NoError(err)
+	assert.Equal(Config{
+		Listen:   testListen{Port: 8080},
+		Upstream: &testUpstream{Addrs: []string{"10.0.0.1"}},
+	}, config)
+
+	err = m.Map(L.GetGlobal("long"), &config)
+	assert.NoError(err)
+	assert.Equal(Config{
+		Listen:   testListen{Host: "0.0.0.0", Port: 8080},
+		Upstream: &testUpstream{Addrs: []string{"10.0.0.1", "10.0.0.2"}},
+	}, config)
+
+	err = m.Map(L.GetGlobal("bad"), &config)
+	assert.EqualError(err, "Listen: Port: int expected but got Lua boolean")
+
+	err = m.Map(lua.LNumber(1), &testRole{})
+	assert.EqualError(err, "gluamapper.testRole expected but got Lua number")
+}
+
+func TestMapScalarToSlice(t *testing.T) {
+	var err error
+	assert := require.New(t)
+
+	m := NewMapper()
+	var addrs []string
+	err = m.Map(lua.LString("10.0.0.1"), &addrs)
+	assert.NoError(err)
+	assert.Equal([]string{"10.0.0.1"}, addrs)
+
+	var ports []int
+	err = m.Map(lua.LNumber(80), &ports)
+	assert.NoError(err)
+	assert.Equal([]int{80}, ports)
+	err = m.Map(lua.LTrue, &ports)
+	assert.EqualError(err, "[]int expected but got Lua boolean")
+
+	var timeouts []time.Duration
+	err = m.Map(lua.LString("1s"), &timeouts)
+	assert.NoError(err)
+	assert.Equal([]time.Duration{time.Second}, timeouts)
+	err = m.Map(lua.LString("1x"), &timeouts)
+	assert.EqualError(err, `slice[0]: time: unknown unit "x" in duration "1x"`)
+}
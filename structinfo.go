@@ -0,0 +1,223 @@
+package gluamapper
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/yuin/gopher-lua"
+)
+
+// structInfo is the metadata of a struct type parsed from the field tags,
+// cached by the type and the tag names of the Mapper.
+type structInfo struct {
+	fields      []fieldInfo // exported fields except the remain field
+	remainIndex int         // index of the field with the tag option "remain", -1 if none
+	keyIndex    int         // index of the field with the tag option "key", -1 if none
+	shorthand   *fieldInfo  // the shorthand field, nil if none
+	maxPos      int         // the max array index of the positional fields, 0 if none
+	err         error       // the error in the tags, such as an illegal pos
+
+	tagName  string   // Mapper.TagName to parse the tags
+	tagNames []string // Mapper.TagNames to parse the tags
+}
+
+// fieldInfo is the metadata of a struct field.
+type fieldInfo struct {
+	index   int
+	name    string     // the Go field name
+	key     *tableKey  // the key in the tag, nil to use the field name
+	nameKey tableKey   // the field name as the key
+	aliases []tableKey // the keys in the tag option "alias"
+	opts    tagOptions
+	pos     int // the array index if positional, otherwise 0
+}
+
+// tableKey is a Lua table key in a struct tag, which may be a dotted key path.
+type tableKey struct {
+	text string   // as written in the tag, like "server.http.port"
+	path []string // split by the dots, nil if the text is a plain key
+}
+
+func newTableKey(text string) tableKey {
+	path := splitKeyPath(text)
+	if len(path) == 1 && path[0] == text {
+		return tableKey{text: text} // not a path
+	}
+	return tableKey{text: text, path: path}
+}
+
+// structInfoCache is the cache of the struct metadata for each struct type,
+// as map[reflect.Type][]*structInfo which is copied on write under structInfoMu.
+// The metadata for the different tag names of the Mappers are listed together.
+var (
+	structInfoCache atomic.Value
+	structInfoMu    sync.Mutex
+)
+
+// getStructInfo gets the cached metadata of the struct type.
+func (m *Mapper) getStructInfo(structType reflect.Type) *structInfo {
+	cache, _ := structInfoCache.Load().(map[reflect.Type][]*structInfo)
+	for _, info := range cache[structType] {
+		if m.hasTagNamesOf(info) {
+			return info
+		}
+	}
+
+	info := m.newStructInfo(structType)
+	structInfoMu.Lock()
+	defer structInfoMu.Unlock()
+	cache, _ = structInfoCache.Load().(map[reflect.Type][]*structInfo)
+	newCache := make(map[reflect.Type][]*structInfo, len(cache)+1)
+	for t, infos := range cache {
+		newCache[t] = infos
+	}
+	infos := cache[structType]
+	newCache[structType] = append(infos[:len(infos):len(infos)], info)
+	structInfoCache.Store(newCache)
+	return info
+}
+
+// hasTagNamesOf reports whether the struct metadata is parsed with the tag names of m.
+func (m *Mapper) hasTagNamesOf(info *structInfo) bool {
+	if info.tagName != m.TagName || len(info.tagNames) != len(m.TagNames) {
+		return false
+	}
+	for i, tagName := range info.tagNames {
+		if tagName != m.TagNames[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func (m *Mapper) newStructInfo(structType reflect.Type) *structInfo {
+	info := &structInfo{
+		remainIndex: -1,
+		keyIndex:    -1,
+		tagName:     m.TagName,
+		tagNames:    append([]string(nil), m.TagNames...),
+	}
+	isTuple := false
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		if field.Name != "_" {
+			continue
+		}
+		if _, opts := m.lookupFieldTag(field); opts != nil {
+			_, isTuple = opts["tuple"]
+		}
+	}
+
+	lastPos := 0
+	posFields := make(map[int]string)
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		if field.PkgPath != "" {
+			continue // unexported field
+		}
+		tagName, opts := m.lookupFieldTag(field)
+		if tagName == "-" {
+			continue // skipped field
+		}
+		if _, ok := opts["remain"]; ok {
+			info.remainIndex = i
+			continue
+		}
+		if _, ok := opts["key"]; ok && info.keyIndex < 0 {
+			info.keyIndex = i
+		}
+
+		fi := fieldInfo{index: i, name: field.Name, nameKey: tableKey{text: field.Name}, opts: opts}
+		if tagName != "" {
+			key := newTableKey(tagName)
+			fi.key = &key
+		}
+		if aliases := opts["alias"]; aliases != "" {
+			for _, alias := range strings.Split(aliases, "|") {
+				fi.aliases = append(fi.aliases, newTableKey(alias))
+			}
+		}
+
+		value, hasPos := opts["pos"]
+		if hasPos || isTuple && tagName == "" {
+			pos := lastPos + 1
+			if hasPos {
+				var err error
+				if pos, err = strconv.Atoi(value); err != nil || pos < 1 {
+					info.err = fmt.Errorf("%s: illegal pos %q", field.Name, value)
+					return info
+				}
+			}
+			if name, ok := posFields[pos]; ok {
+				info.err = fmt.Errorf("%s: duplicate pos %d of %s", field.Name, pos, name)
+				return info
+			}
+			posFields[pos] = field.Name
+			fi.pos = pos
+			if pos > info.maxPos {
+				info.maxPos = pos
+			}
+			lastPos = pos
+		}
+		info.fields = append(info.fields, fi)
+	}
+	info.shorthand = info.getShorthandField(structType)
+	return info
+}
+
+// getShorthandField gets the shorthand field of the struct type
+// by the LuaShorthand interface, or by the tag option "shorthand".
+func (info *structInfo) getShorthandField(structType reflect.Type) *fieldInfo {
+	name := ""
+	if reflect.PtrTo(structType).Implements(luaShorthandType) {
+		name = reflect.New(structType).Interface().(LuaShorthand).LuaShorthandField()
+	}
+	for i := range info.fields {
+		fi := &info.fields[i]
+		if name != "" && fi.name == name {
+			return fi
+		}
+		if _, ok := fi.opts["shorthand"]; ok && name == "" {
+			return fi
+		}
+	}
+	return nil
+}
+
+// getFieldKey gets the Lua table key of the struct field,
+// which is the key in the tag, or the field name converted by m.NameFunc.
+func (m *Mapper) getFieldKey(fi *fieldInfo) *tableKey {
+	if fi.key != nil {
+		return fi.key
+	}
+	if m.NameFunc != nil {
+		return &tableKey{text: m.NameFunc(fi.name)}
+	}
+	return &fi.nameKey
+}
+
+// getTableField gets the value of the key from the Lua table,
+// walking the nested Lua tables if the key is a dotted key path.
+// Returns Lua nil if any table in the path is absent.
+func (m *Mapper) getTableField(tbl *lua.LTable, key *tableKey) (lua.LValue, error) {
+	if key.path == nil {
+		return m.getTableKey(tbl, key.text)
+	}
+	return m.getTablePath(tbl, key.path)
+}
+
+// setTableField sets the value of the key into the Lua table,
+// creating the absent nested tables if the key is a dotted key path.
+func setTableField(L *lua.LState, tbl *lua.LTable, key *tableKey, lv lua.LValue) error {
+	if key.path == nil {
+		if lv != lua.LNil {
+			tbl.RawSetString(key.text, lv)
+		}
+		return nil
+	}
+	return setTablePath(L, tbl, key.path, lv)
+}
@@ -0,0 +1,134 @@
+package gluamapper
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"time"
+
+	assert "github.com/arl/assertgo"
+	"github.com/yuin/gopher-lua"
+)
+
+var (
+	durationType    = reflect.TypeOf(time.Duration(0))
+	timeType        = reflect.TypeOf(time.Time{})
+	locationPtrType = reflect.TypeOf((*time.Location)(nil))
+)
+
+// timeLayouts is the named layouts which can be used in the tag option "layout".
+var timeLayouts = map[string]string{
+	"ANSIC":       time.ANSIC,
+	"UnixDate":    time.UnixDate,
+	"RubyDate":    time.RubyDate,
+	"RFC822":      time.RFC822,
+	"RFC822Z":     time.RFC822Z,
+	"RFC850":      time.RFC850,
+	"RFC1123":     time.RFC1123,
+	"RFC1123Z":    time.RFC1123Z,
+	"RFC3339":     time.RFC3339,
+	"RFC3339Nano": time.RFC3339Nano,
+	"Kitchen":     time.Kitchen,
+	"DateTime":    "2006-01-02 15:04:05",
+	"DateOnly":    "2006-01-02",
+	"TimeOnly":    "15:04:05",
+}
+
+// mapDuration maps a Lua string like "1m30s" or a Lua number into time.Duration.
+// The unit of the number is the tag option "unit", such as "ms",
+// or m.DurationUnit if no unit option, or nanosecond if m.DurationUnit is 0.
+func (m *Mapper) mapDuration(lv lua.LValue, rv reflect.Value, opts tagOptions) error {
+	assert.True(rv.Type() == durationType)
+	switch v := lv.(type) {
+	case lua.LString:
+		d, err := time.ParseDuration(string(v))
+		if err != nil {
+			return err
+		}
+		rv.SetInt(int64(d))
+		return nil
+	case lua.LNumber:
+		unit, err := getTimeUnit(opts, m.DurationUnit)
+		if err != nil {
+			return err
+		}
+		d := float64(v) * float64(unit)
+		if math.IsNaN(d) || d < math.MinInt64 || d >= math.MaxInt64 {
+			return fmt.Errorf("%v overflows %s", v, rv.Type())
+		}
+		rv.SetInt(int64(math.Round(d)))
+		return nil
+	}
+	return newTypeError(lv, rv)
+}
+
+// mapTime maps a Lua string or a Lua number into time.Time.
+// The string is parsed by the layout of the tag option "layout",
+// which is a layout like "2006-01-02" or the name of a layout like "RFC1123",
+// or RFC 3339 if no layout option.
+// The number is the time since the Unix epoch in the tag option "unit",
+// or in seconds if no unit option.
+func mapTime(lv lua.LValue, rv reflect.Value, opts tagOptions) error {
+	assert.True(rv.Type() == timeType)
+	switch v := lv.(type) {
+	case lua.LString:
+		layout := time.RFC3339
+		if optLayout := opts["layout"]; optLayout != "" {
+			layout = optLayout
+			if named, ok := timeLayouts[optLayout]; ok {
+				layout = named
+			}
+		}
+		t, err := time.Parse(layout, string(v))
+		if err != nil {
+			return err
+		}
+		rv.Set(reflect.ValueOf(t))
+		return nil
+	case lua.LNumber:
+		unit, err := getTimeUnit(opts, time.Second)
+		if err != nil {
+			return err
+		}
+		sec, frac := math.Modf(float64(v) * unit.Seconds())
+		if math.IsNaN(sec) || sec < math.MinInt64 || sec >= math.MaxInt64 {
+			return fmt.Errorf("%v overflows %s", v, rv.Type())
+		}
+		t := time.Unix(int64(sec), int64(math.Round(frac*1e9))).UTC()
+		rv.Set(reflect.ValueOf(t))
+		return nil
+	}
+	return newTypeError(lv, rv)
+}
+
+// mapLocation maps a Lua string of IANA time zone name like "America/New_York" into *time.Location.
+func mapLocation(lv lua.LValue, rv reflect.Value) error {
+	assert.True(rv.Type() == locationPtrType)
+	name, ok := lv.(lua.LString)
+	if !ok {
+		return newTypeError(lv, rv)
+	}
+	loc, err := time.LoadLocation(string(name))
+	if err != nil {
+		return err
+	}
+	rv.Set(reflect.ValueOf(loc))
+	return nil
+}
+
+// getTimeUnit gets the time unit from the tag option "unit" like "ms".
+// Returns defaultUnit if no unit option, or nanosecond if defaultUnit is 0.
+func getTimeUnit(opts tagOptions, defaultUnit time.Duration) (time.Duration, error) {
+	optUnit := opts["unit"]
+	if optUnit == "" {
+		if defaultUnit == 0 {
+			return time.Nanosecond, nil
+		}
+		return defaultUnit, nil
+	}
+	unit, err := time.ParseDuration("1" + optUnit)
+	if err != nil {
+		return 0, fmt.Errorf("illegal time unit %q", optUnit)
+	}
+	return unit, nil
+}
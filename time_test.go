@@ -0,0 +1,114 @@
+package gluamapper
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yuin/gopher-lua"
+)
+
+func TestMapDuration(t *testing.T) {
+	var err error
+	assert := require.New(t)
+	L := lua.NewState()
+
+	var d time.Duration
+	err = Map(lua.LString("1m30s"), &d)
+	assert.NoError(err)
+	assert.Equal(90*time.Second, d)
+	err = Map(lua.LNumber(1000), &d)
+	assert.NoError(err)
+	assert.Equal(time.Microsecond, d)
+	err = Map(lua.LString("abc"), &d)
+	assert.Error(err)
+	err = Map(lua.LTrue, &d)
+	assert.EqualError(err, "time.Duration expected but got Lua boolean")
+
+	m := NewMapperWithTagName("lua")
+	m.DurationUnit = time.Second
+	err = m.Map(lua.LNumber(1.5), &d)
+	assert.NoError(err)
+	assert.Equal(1500*time.Millisecond, d)
+	err = m.Map(lua.LNumber(1e20), &d)
+	assert.EqualError(err, "1e+20 overflows time.Duration")
+
+	type Config struct {
+		Timeout  time.Duration   `lua:"timeout,unit=ms"`
+		Retries  []time.Duration `lua:"retries,unit=h"`
+		Interval time.Duration   `lua:"interval,unit=year"`
+	}
+	err = L.DoString(`cfg = {timeout = 250, retries = {1, "2m"}}`)
+	assert.NoError(err)
+	var cfg Config
+	err = m.Map(L.GetGlobal("cfg"), &cfg)
+	assert.NoError(err)
+	assert.Equal(250*time.Millisecond, cfg.Timeout)
+	assert.Equal([]time.Duration{time.Hour, 2 * time.Minute}, cfg.Retries)
+	err = L.DoString(`cfg = {interval = 1}`)
+	assert.NoError(err)
+	err = m.Map(L.GetGlobal("cfg"), &cfg)
+	assert.EqualError(err, `Interval: illegal time unit "year"`)
+}
+
+func TestMapTime(t *testing.T) {
+	var err error
+	assert := require.New(t)
+	L := lua.NewState()
+
+	var tm time.Time
+	err = Map(lua.LString("2020-12-24T10:20:30Z"), &tm)
+	assert.NoError(err)
+	assert.Equal(time.Date(2020, 12, 24, 10, 20, 30, 0, time.UTC), tm)
+	err = Map(lua.LNumber(1608805230.5), &tm)
+	assert.NoError(err)
+	assert.Equal(time.Date(2020, 12, 24, 10, 20, 30, 5e8, time.UTC), tm)
+	err = Map(lua.LString("2020-12-24"), &tm)
+	assert.Error(err)
+	err = Map(L.NewTable(), &tm)
+	assert.EqualError(err, "time.Time expected but got Lua table")
+
+	type Schedule struct {
+		Day    time.Time  `lua:"day,layout=2006-01-02"`
+		Update *time.Time `lua:"update,layout=RFC1123"`
+		Since  time.Time  `lua:"since,unit=ms"`
+	}
+	err = L.DoString(`s = {day = "2020-12-24", update = "Thu, 24 Dec 2020 10:20:30 UTC", since = 1000}`)
+	assert.NoError(err)
+	var s Schedule
+	err = NewMapperWithTagName("lua").Map(L.GetGlobal("s"), &s)
+	assert.NoError(err)
+	assert.Equal(time.Date(2020, 12, 24, 0, 0, 0, 0, time.UTC), s.Day)
+	assert.Equal(2020, s.Update.Year())
+	assert.Equal(time.Unix(1, 0).UTC(), s.Since)
+}
+
+func TestMapLocation(t *testing.T) {
+	var err error
+	assert := require.New(t)
+
+	var loc *time.Location
+	err = Map(lua.LString("UTC"), &loc)
+	assert.NoError(err)
+	assert.Equal(time.UTC, loc)
+	err = Map(lua.LString("No/Such_Zone"), &loc)
+	assert.Error(err)
+	err = Map(lua.LNumber(1), &loc)
+	assert.EqualError(err, "*time.Location expected but got Lua number")
+}
+
+func TestEncodeTime(t *testing.T) {
+	assert := require.New(t)
+	L := lua.NewState()
+	m := NewMapper()
+
+	lv, err := m.Encode(L, 90*time.Second)
+	assert.NoError(err)
+	assert.Equal(lua.LString("1m30s"), lv)
+	lv, err = m.Encode(L, time.Date(2020, 12, 24, 10, 20, 30, 0, time.UTC))
+	assert.NoError(err)
+	assert.Equal(lua.LString("2020-12-24T10:20:30Z"), lv)
+	lv, err = m.Encode(L, time.UTC)
+	assert.NoError(err)
+	assert.Equal(lua.LString("UTC"), lv)
+}
@@ -0,0 +1,31 @@
+package gluamapper
+
+import (
+	"fmt"
+
+	"github.com/yuin/gopher-lua"
+)
+
+// lookupPositionalField gets the Lua value of the positional struct field by the array index.
+// The positional fields have the tag option "pos" like `lua:",pos=1"`,
+// or have no tag name if a blank field "_" has the tag option "tuple".
+// If the field also has a tag name, the value can be set by the key instead.
+func (m *Mapper) lookupPositionalField(tbl *lua.LTable, fi *fieldInfo, keys *consumedKeys) (lua.LValue, error) {
+	lv := tbl.RawGetInt(fi.pos)
+	if fi.key == nil {
+		return lv, nil
+	}
+
+	keys.add(fi.key)
+	namedVal, err := m.getTableField(tbl, fi.key)
+	if err != nil {
+		return lua.LNil, err
+	}
+	if namedVal == lua.LNil {
+		return lv, nil
+	}
+	if lv != lua.LNil {
+		return lua.LNil, fmt.Errorf("conflicting keys [%d] and %q", fi.pos, fi.key.text)
+	}
+	return namedVal, nil
+}
@@ -0,0 +1,110 @@
+package gluamapper
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yuin/gopher-lua"
+)
+
+func TestMapTuple(t *testing.T) {
+	var err error
+	assert := require.New(t)
+	L := lua.NewState()
+	err = L.DoString(`
+		point = {10, 20}
+		named_point = {x = 1, y = 2}
+		range = {1, 100, step = 5}
+		long_point = {1, 2, 3}
+	`)
+	assert.NoError(err)
+
+	type Point struct {
+		_ struct{} `lua:",tuple"`
+		X int
+		Y int
+	}
+	m := NewMapperWithTagName("lua")
+	var point Point
+	err = m.Map(L.GetGlobal("point"), &point)
+	assert.NoError(err)
+	assert.Equal(Point{X: 10, Y: 20}, point)
+	err = m.Map(L.GetGlobal("long_point"), &point)
+	assert.EqualError(err, "excess elements: 2 expected but got 3")
+
+	lv, err := m.Encode(L, Point{X: 3, Y: 4})
+	assert.NoError(err)
+	assert.Equal(2, lv.(*lua.LTable).Len())
+	assert.Equal(lua.LNumber(3), lv.(*lua.LTable).RawGetInt(1))
+	assert.Equal(lua.LNumber(4), lv.(*lua.LTable).RawGetInt(2))
+	assert.Equal(lua.LNil, lv.(*lua.LTable).RawGetString("X"))
+
+	type Range struct {
+		Start int `lua:",pos=1"`
+		End   int `lua:",pos=2"`
+		Step  int `lua:"step"`
+	}
+	var rng Range
+	err = m.Map(L.GetGlobal("range"), &rng)
+	assert.NoError(err)
+	assert.Equal(Range{Start: 1, End: 100, Step: 5}, rng)
+
+	lv, err = m.Encode(L, rng)
+	assert.NoError(err)
+	var decoded Range
+	assert.NoError(m.Map(lv, &decoded))
+	assert.Equal(rng, decoded)
+
+	type NamedPoint struct {
+		X int `lua:"x,pos=1"`
+		Y int `lua:"y,pos=2"`
+	}
+	var namedPoint NamedPoint
+	err = m.Map(L.GetGlobal("named_point"), &namedPoint)
+	assert.NoError(err)
+	assert.Equal(NamedPoint{X: 1, Y: 2}, namedPoint)
+	err = m.Map(L.GetGlobal("point"), &namedPoint)
+	assert.NoError(err)
+	assert.Equal(NamedPoint{X: 10, Y: 20}, namedPoint)
+}
+
+func TestMapTupleErrors(t *testing.T) {
+	var err error
+	assert := require.New(t)
+	L := lua.NewState()
+	err = L.DoString(`point = {1, 2, 3, x = 4}`)
+	assert.NoError(err)
+
+	m := NewMapperWithTagName("lua")
+	type ConflictPoint struct {
+		X int `lua:"x,pos=1"`
+	}
+	err = m.Map(L.GetGlobal("point"), &ConflictPoint{})
+	assert.EqualError(err, `X: conflicting keys [1] and "x"`)
+
+	type IllegalPoint struct {
+		X int `lua:",pos=0"`
+	}
+	err = m.Map(L.GetGlobal("point"), &IllegalPoint{})
+	assert.EqualError(err, `X: illegal pos "0"`)
+
+	type DuplicatePoint struct {
+		X int `lua:",pos=1"`
+		Y int `lua:",pos=1"`
+	}
+	err = m.Map(L.GetGlobal("point"), &DuplicatePoint{})
+	assert.EqualError(err, "Y: duplicate pos 1 of X")
+
+	type RemainPoint struct {
+		_     struct{} `lua:",tuple"`
+		X     int
+		Extra *lua.LTable `lua:",remain"`
+	}
+	var remainPoint RemainPoint
+	err = m.Map(L.GetGlobal("point"), &remainPoint)
+	assert.NoError(err)
+	assert.Equal(1, remainPoint.X)
+	assert.Equal(lua.LNil, remainPoint.Extra.RawGetInt(1))
+	assert.Equal(lua.LNumber(3), remainPoint.Extra.RawGetInt(3))
+	assert.Equal(lua.LNumber(4), remainPoint.Extra.RawGetString("x"))
+}
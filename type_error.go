@@ -12,7 +12,7 @@ type TypeError struct {
 	goType  reflect.Type
 	luaType lua.LValueType
 
-	// if luaType is LTUserData
+	// if luaType is LTUserData, the value is nil or a nil pointer of luaUserDataValueType
 	isLuaUserDataValueNil bool
 	luaUserDataValueType  reflect.Type
 }
@@ -36,7 +36,10 @@ func newTypeError(lv lua.LValue, rv reflect.Value) *TypeError {
 	}
 
 	val := reflect.ValueOf(ud.Value)
-	result.luaUserDataValueType = reflect.Indirect(val).Type()
+	result.luaUserDataValueType = val.Type()
+	if val.Kind() == reflect.Ptr && val.IsNil() {
+		result.isLuaUserDataValueNil = true // a typed nil pointer
+	}
 	return result
 }
 
@@ -45,6 +48,9 @@ func (t *TypeError) Error() string {
 		return fmt.Sprintf("%s expected but got Lua %s", t.goType, t.luaType)
 	}
 	if t.isLuaUserDataValueNil {
+		if t.luaUserDataValueType != nil {
+			return fmt.Sprintf("%s expected but got Lua user data of nil %s", t.goType, t.luaUserDataValueType)
+		}
 		return fmt.Sprintf("%s expected but got Lua user data of nil", t.goType)
 	}
 	return fmt.Sprintf("%s expected but got Lua user data of %s", t.goType, t.luaUserDataValueType)
@@ -0,0 +1,153 @@
+package gluamapper
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// byteSizeSuffixes is the multipliers of the byte size suffixes.
+// The suffixes are case-sensitive, and the ambiguous ones such as "mb" and "Kb" are absent.
+var byteSizeSuffixes = map[string]int64{
+	"": 1, "B": 1,
+	"k": 1e3, "K": 1e3, "kB": 1e3, "KB": 1e3,
+	"M": 1e6, "MB": 1e6,
+	"G": 1e9, "GB": 1e9,
+	"T": 1e12, "TB": 1e12,
+	"P": 1e15, "PB": 1e15,
+	"E": 1e18, "EB": 1e18,
+	"Ki": 1 << 10, "KiB": 1 << 10,
+	"Mi": 1 << 20, "MiB": 1 << 20,
+	"Gi": 1 << 30, "GiB": 1 << 30,
+	"Ti": 1 << 40, "TiB": 1 << 40,
+	"Pi": 1 << 50, "PiB": 1 << 50,
+	"Ei": 1 << 60, "EiB": 1 << 60,
+}
+
+// isNumberKind reports whether k is an integer or float kind.
+func isNumberKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// mapUnitString maps a Lua string with a unit into a number, according to the tag option "unit":
+//
+//	unit=bytes    "10MB", "512KiB" or "1.5G", with SI or IEC suffixes
+//	unit=percent  "75%", which is 0.75
+//	unit=rate     "100/s" or "6000/m", in number per second
+//
+// The string is parsed exactly, and an error is returned if the result
+// is not an integer for integer kinds, or overflows the Go type.
+func mapUnitString(s string, rv reflect.Value, unit string) error {
+	var rat *big.Rat
+	var err error
+	switch unit {
+	case "bytes":
+		rat, err = parseByteSize(s)
+	case "percent":
+		rat, err = parsePercent(s)
+	case "rate":
+		rat, err = parseRate(s)
+	default:
+		return fmt.Errorf("unknown unit %q", unit)
+	}
+	if err != nil {
+		return err
+	}
+	return setRat(rv, rat, s)
+}
+
+func parseByteSize(s string) (*big.Rat, error) {
+	num, suffix := splitNumber(s)
+	multiplier, ok := byteSizeSuffixes[suffix]
+	if !ok {
+		return nil, fmt.Errorf("ambiguous or unknown byte size suffix %q in %q", suffix, s)
+	}
+	rat, ok := new(big.Rat).SetString(num)
+	if !ok {
+		return nil, fmt.Errorf("illegal byte size %q", s)
+	}
+	return rat.Mul(rat, new(big.Rat).SetInt64(multiplier)), nil
+}
+
+func parsePercent(s string) (*big.Rat, error) {
+	num, suffix := splitNumber(s)
+	rat, ok := new(big.Rat).SetString(num)
+	if suffix != "%" || !ok {
+		return nil, fmt.Errorf("illegal percentage %q", s)
+	}
+	return rat.Quo(rat, big.NewRat(100, 1)), nil
+}
+
+func parseRate(s string) (*big.Rat, error) {
+	num, suffix := splitNumber(s)
+	rat, ok := new(big.Rat).SetString(num)
+	if !strings.HasPrefix(suffix, "/") || !ok {
+		return nil, fmt.Errorf("illegal rate %q", s)
+	}
+	period := strings.TrimSpace(suffix[1:])
+	if period != "" && (period[0] < '0' || period[0] > '9') && period[0] != '.' {
+		period = "1" + period // "/s" is per 1s
+	}
+	per, err := time.ParseDuration(period)
+	if err != nil || per <= 0 {
+		return nil, fmt.Errorf("illegal rate %q", s)
+	}
+	// rate per second = num * 1e9 / per
+	rat.Mul(rat, big.NewRat(int64(time.Second), int64(per)))
+	return rat, nil
+}
+
+// splitNumber splits s into the leading decimal number and the trimmed suffix.
+// Exponents are not part of the number, because "E" is a suffix.
+func splitNumber(s string) (num string, suffix string) {
+	s = strings.TrimSpace(s)
+	i := 0
+	if i < len(s) && (s[i] == '+' || s[i] == '-') {
+		i++
+	}
+	for i < len(s) && (s[i] >= '0' && s[i] <= '9' || s[i] == '.') {
+		i++
+	}
+	return s[:i], strings.TrimSpace(s[i:])
+}
+
+// setRat sets the exact rational number into the number value.
+func setRat(rv reflect.Value, rat *big.Rat, s string) error {
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if !rat.IsInt() {
+			return fmt.Errorf("%q is not an integer", s)
+		}
+		n := rat.Num()
+		if !n.IsInt64() || rv.OverflowInt(n.Int64()) {
+			return fmt.Errorf("%q overflows %s", s, rv.Type())
+		}
+		rv.SetInt(n.Int64())
+		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if !rat.IsInt() {
+			return fmt.Errorf("%q is not an integer", s)
+		}
+		n := rat.Num()
+		if !n.IsUint64() || rv.OverflowUint(n.Uint64()) {
+			return fmt.Errorf("%q overflows %s", s, rv.Type())
+		}
+		rv.SetUint(n.Uint64())
+		return nil
+	}
+
+	f, _ := rat.Float64()
+	if rv.OverflowFloat(f) {
+		return fmt.Errorf("%q overflows %s", s, rv.Type())
+	}
+	rv.SetFloat(f)
+	return nil
+}
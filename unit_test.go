@@ -0,0 +1,65 @@
+package gluamapper
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yuin/gopher-lua"
+)
+
+func TestMapUnit(t *testing.T) {
+	var err error
+	assert := require.New(t)
+	L := lua.NewState()
+	m := NewMapperWithTagName("lua")
+
+	type Limits struct {
+		MaxBody  int64   `lua:"max_body,unit=bytes"`
+		MaxConns uint16  `lua:"max_conns,unit=bytes"`
+		Usage    float64 `lua:"usage,unit=percent"`
+		Rate     float32 `lua:"rate,unit=rate"`
+		Bad      int     `lua:"bad,unit=furlong"`
+	}
+	tests := []struct {
+		lua    string
+		limits Limits
+		err    string
+	}{
+		{lua: `{max_body = "10MB"}`, limits: Limits{MaxBody: 10000000}},
+		{lua: `{max_body = "512KiB"}`, limits: Limits{MaxBody: 512 * 1024}},
+		{lua: `{max_body = "1.5G"}`, limits: Limits{MaxBody: 1500000000}},
+		{lua: `{max_body = " 2 EiB "}`, limits: Limits{MaxBody: 2 << 60}},
+		{lua: `{max_body = "123"}`, limits: Limits{MaxBody: 123}},
+		{lua: `{max_body = 123}`, limits: Limits{MaxBody: 123}},
+		{lua: `{max_body = "8EiB"}`, err: `MaxBody: "8EiB" overflows int64`},
+		{lua: `{max_body = "0.5B"}`, err: `MaxBody: "0.5B" is not an integer`},
+		{lua: `{max_body = "10mb"}`, err: `MaxBody: ambiguous or unknown byte size suffix "mb" in "10mb"`},
+		{lua: `{max_body = "1e3"}`, err: `MaxBody: ambiguous or unknown byte size suffix "e3" in "1e3"`},
+		{lua: `{max_body = "KB"}`, err: `MaxBody: illegal byte size "KB"`},
+		{lua: `{max_conns = "64Ki"}`, err: `MaxConns: "64Ki" overflows uint16`},
+		{lua: `{max_conns = "-1"}`, err: `MaxConns: "-1" overflows uint16`},
+		{lua: `{usage = "75%"}`, limits: Limits{Usage: 0.75}},
+		{lua: `{usage = 0.5}`, limits: Limits{Usage: 0.5}},
+		{lua: `{usage = "75"}`, err: `Usage: illegal percentage "75"`},
+		{lua: `{rate = "100/s"}`, limits: Limits{Rate: 100}},
+		{lua: `{rate = "6000/m"}`, limits: Limits{Rate: 100}},
+		{lua: `{rate = "1/ms"}`, limits: Limits{Rate: 1000}},
+		{lua: `{rate = "1/day"}`, err: `Rate: illegal rate "1/day"`},
+		{lua: `{rate = "30/1.5s"}`, limits: Limits{Rate: 20}},
+		{lua: `{rate = "600/10m"}`, limits: Limits{Rate: 1}},
+		{lua: `{rate = "100/0s"}`, err: `Rate: illegal rate "100/0s"`},
+		{lua: `{bad = "1"}`, err: `Bad: unknown unit "furlong"`},
+	}
+	for _, test := range tests {
+		err = L.DoString("limits = " + test.lua)
+		assert.NoError(err)
+		var limits Limits
+		err = m.Map(L.GetGlobal("limits"), &limits)
+		if test.err != "" {
+			assert.EqualError(err, test.err, test.lua)
+			continue
+		}
+		assert.NoError(err, test.lua)
+		assert.Equal(test.limits, limits, test.lua)
+	}
+}
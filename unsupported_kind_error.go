@@ -0,0 +1,16 @@
+package gluamapper
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// UnsupportedKindError is returned when mapping into a Go type
+// whose kind can not be mapped, such as unsafe.Pointer.
+type UnsupportedKindError struct {
+	goType reflect.Type
+}
+
+func (u *UnsupportedKindError) Error() string {
+	return fmt.Sprintf("unsupported kind %s of %s", u.goType.Kind(), u.goType)
+}
@@ -0,0 +1,104 @@
+package gluamapper
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	assert "github.com/arl/assertgo"
+	"github.com/yuin/gopher-lua"
+)
+
+// variantInfo is the concrete types registered for an interface type.
+type variantInfo struct {
+	types map[string]reflect.Type
+	names map[reflect.Type]string
+}
+
+// RegisterType registers the concrete type for the discriminator value of the interface type.
+// A Lua table like {type="s3", bucket="b"} is then mapped into the interface
+// by creating a value of the concrete type registered for "s3" and mapping the table into it.
+// See DiscriminatorKey for the key name.
+// Encode writes the discriminator value back into the encoded table.
+// RegisterType panics if ifaceType is not an interface or concreteType does not implement it.
+// It must not be called concurrently with Map.
+func (m *Mapper) RegisterType(ifaceType reflect.Type, discriminator string, concreteType reflect.Type) {
+	if ifaceType.Kind() != reflect.Interface {
+		panic(fmt.Sprintf("%s is not an interface", ifaceType))
+	}
+	if !concreteType.Implements(ifaceType) {
+		panic(fmt.Sprintf("%s does not implement %s", concreteType, ifaceType))
+	}
+
+	if m.variants == nil {
+		m.variants = make(map[reflect.Type]*variantInfo)
+	}
+	info := m.variants[ifaceType]
+	if info == nil {
+		info = &variantInfo{
+			types: make(map[string]reflect.Type),
+			names: make(map[reflect.Type]string),
+		}
+		m.variants[ifaceType] = info
+	}
+	info.types[discriminator] = concreteType
+	info.names[concreteType] = discriminator
+}
+
+func (m *Mapper) getDiscriminatorKey() string {
+	if m.DiscriminatorKey == "" {
+		return "type"
+	}
+	return m.DiscriminatorKey
+}
+
+// mapVariant maps a Lua table into the registered concrete type of the interface.
+func (m *Mapper) mapVariant(info *variantInfo, lv lua.LValue, rv reflect.Value) error {
+	assert.True(rv.Kind() == reflect.Interface)
+	tbl, ok := lv.(*lua.LTable)
+	if !ok {
+		return newTypeError(lv, rv)
+	}
+	key := m.getDiscriminatorKey()
+	var discriminator string
+	if err := m.MapValue(tbl.RawGetString(key), reflect.ValueOf(&discriminator).Elem()); err != nil {
+		return fmt.Errorf("%s: %w", key, err)
+	}
+	concreteType, ok := info.types[discriminator]
+	if !ok {
+		return fmt.Errorf("%s: unknown %s %q, valid choices: %s",
+			key, rv.Type(), discriminator, strings.Join(info.sortedNames(), ", "))
+	}
+
+	value := reflect.New(concreteType).Elem()
+	if err := m.mapNonNilValue(tbl, value, tagOptions{"discriminator": key}); err != nil {
+		return err
+	}
+	rv.Set(value)
+	return nil
+}
+
+func (info *variantInfo) sortedNames() []string {
+	names := make([]string, 0, len(info.types))
+	for name := range info.types {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// encodeVariant encodes the interface value, and writes the discriminator
+// into the encoded table if the dynamic type is registered.
+func (m *Mapper) encodeVariant(L *lua.LState, info *variantInfo, rv reflect.Value, opts tagOptions) (lua.LValue, error) {
+	assert.True(rv.Kind() == reflect.Interface && !rv.IsNil())
+	lv, err := m.encodeValue(L, rv.Elem(), opts)
+	if err != nil {
+		return lua.LNil, err
+	}
+	name, ok := info.names[rv.Elem().Type()]
+	if tbl, isTable := lv.(*lua.LTable); ok && isTable {
+		tbl.RawSetString(m.getDiscriminatorKey(), lua.LString(name))
+	}
+	return lv, nil
+}
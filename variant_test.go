@@ -0,0 +1,86 @@
+package gluamapper
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yuin/gopher-lua"
+)
+
+type testStorage interface {
+	Kind() string
+}
+
+type testS3Storage struct {
+	Bucket string `lua:"bucket"`
+}
+
+func (s *testS3Storage) Kind() string { return "s3" }
+
+type testLocalStorage struct {
+	Path string `lua:"path"`
+}
+
+func (s testLocalStorage) Kind() string { return "local" }
+
+func TestMapVariant(t *testing.T) {
+	var err error
+	assert := require.New(t)
+	L := lua.NewState()
+	m := NewMapperWithTagName("lua")
+	storageType := reflect.TypeOf((*testStorage)(nil)).Elem()
+	m.RegisterType(storageType, "s3", reflect.TypeOf(&testS3Storage{}))
+	m.RegisterType(storageType, "local", reflect.TypeOf(testLocalStorage{}))
+
+	type Config struct {
+		Storage  testStorage   `lua:"storage"`
+		Backups  []testStorage `lua:"backups"`
+		Fallback testStorage   `lua:"fallback"`
+	}
+	err = L.DoString(`
+		cfg = {
+			storage = {type = "s3", bucket = "b"},
+			backups = {{type = "local", path = "/tmp"}},
+		}
+	`)
+	assert.NoError(err)
+	var cfg Config
+	err = m.Map(L.GetGlobal("cfg"), &cfg)
+	assert.NoError(err)
+	expected := Config{
+		Storage: &testS3Storage{Bucket: "b"},
+		Backups: []testStorage{testLocalStorage{Path: "/tmp"}},
+	}
+	assert.Equal(expected, cfg)
+
+	lv, err := m.Encode(L, &cfg)
+	assert.NoError(err)
+	storage := lv.(*lua.LTable).RawGetString("storage").(*lua.LTable)
+	assert.Equal(lua.LString("s3"), storage.RawGetString("type"))
+	var decoded Config
+	err = m.Map(lv, &decoded)
+	assert.NoError(err)
+	assert.Equal(expected, decoded)
+
+	err = L.DoString(`cfg = {storage = {type = "ftp"}}`)
+	assert.NoError(err)
+	err = m.Map(L.GetGlobal("cfg"), &cfg)
+	assert.EqualError(err, `Storage: type: unknown gluamapper.testStorage "ftp", valid choices: local, s3`)
+	err = L.DoString(`cfg = {storage = {kind = "s3", bucket = 1}}`)
+	assert.NoError(err)
+	err = m.Map(L.GetGlobal("cfg"), &cfg)
+	assert.EqualError(err, `Storage: type: unknown gluamapper.testStorage "", valid choices: local, s3`)
+	m.DiscriminatorKey = "kind"
+	err = m.Map(L.GetGlobal("cfg"), &cfg)
+	assert.EqualError(err, "Storage: Bucket: string expected but got Lua number")
+	err = m.Map(lua.LString("s3"), &cfg.Storage)
+	assert.EqualError(err, "gluamapper.testStorage expected but got Lua string")
+
+	assert.Panics(func() {
+		m.RegisterType(reflect.TypeOf(""), "a", reflect.TypeOf(""))
+	})
+	assert.Panics(func() {
+		m.RegisterType(storageType, "s3", reflect.TypeOf(testS3Storage{}))
+	})
+}